@@ -0,0 +1,288 @@
+package main
+
+// This file contains functionality pertaining to creating distribution archives in-process
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var zipMinimumModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC) // Earliest time zip's MS-DOS timestamps can represent
+
+// GetArchiveExtension will return the file extension of an archive in the provided format, such as .tar.zst for zstd
+func GetArchiveExtension(format string) string {
+	var extension string
+
+	switch format {
+	case "bzip2": // bzip2 doesn't use .bzip2
+		extension = ".tar.bz2"
+	case "gzip": // gzip doesn't use .gzip
+		extension = ".tar.gz"
+	case "lzma":
+		extension = ".tar.lzma"
+	case "xz":
+		extension = ".tar.xz"
+	case "zip":
+		extension = ".zip"
+	case "zstd": // zstd doesn't use .zstd
+		extension = ".tar.zst"
+	}
+
+	return extension
+}
+
+// NewCompressor will return a writer compressing to the provided writer with the provided compressor
+func NewCompressor(w io.Writer, compressor string) (compressorWriter io.WriteCloser, compressorErr error) {
+	switch compressor {
+	case "bzip2":
+		compressorWriter = NewBzip2Writer(w, 9)
+	case "gzip":
+		compressorWriter, compressorErr = gzip.NewWriterLevel(w, gzip.BestCompression)
+	case "lzma":
+		compressorWriter, compressorErr = lzma.NewWriter(w)
+	case "xz":
+		compressorWriter, compressorErr = xz.NewWriter(w)
+	case "zstd":
+		compressorWriter, compressorErr = zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBetterCompression), zstd.WithEncoderConcurrency(1)) // Single threaded, so our output is the same on every system
+	default:
+		compressorErr = errors.New("unsupported compressor: " + compressor)
+	}
+
+	return
+}
+
+// GetArchiveFiles will return the slash separated paths of every file and directory within the provided directory, relative to it and sorted
+func GetArchiveFiles(dir string) (files []string, walkErr error) {
+	walkErr = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != dir { // Not the root itself
+			relPath, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(relPath))
+		}
+
+		return nil
+	})
+
+	sort.Strings(files) // Same order regardless of the filesystem
+	return
+}
+
+// GetSourceDateEpoch will return the time every file in our archives is clamped to, being SOURCE_DATE_EPOCH or the time of the last git commit
+// If neither is available, we use the Unix epoch so our archives are still reproducible
+func GetSourceDateEpoch() time.Time {
+	if epoch, parseErr := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); parseErr == nil { // Set by the environment
+		return time.Unix(epoch, 0).UTC()
+	}
+
+	gitCmd := exec.Command("git", "log", "-1", "--format=%ct")
+	gitCmd.Dir = workdir
+
+	if output, gitErr := gitCmd.Output(); gitErr == nil { // Have a commit
+		if epoch, parseErr := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64); parseErr == nil {
+			return time.Unix(epoch, 0).UTC()
+		}
+	}
+
+	return time.Unix(0, 0).UTC()
+}
+
+// GetArchiveModTime will return the modification time of the file, clamped to the provided epoch
+func GetArchiveModTime(info os.FileInfo, epoch time.Time) time.Time {
+	if modTime := info.ModTime().UTC().Truncate(time.Second); modTime.Before(epoch) { // Older than our epoch
+		return modTime
+	}
+
+	return epoch
+}
+
+// GetArchiveMode will return the normalized permissions of the file, being 0755 for directories and executables, otherwise 0644
+func GetArchiveMode(info os.FileInfo) os.FileMode {
+	if info.IsDir() || info.Mode().Perm()&0111 != 0 { // Directory or executable
+		return 0755
+	}
+
+	return 0644
+}
+
+// CreateArchive will create an archive in the provided format of the contents of dir, only replacing any existing archive once it is complete
+func CreateArchive(dir, archivePath, format string) (createErr error) {
+	partialPath := archivePath + ".partial"
+	var archiveFile *os.File
+
+	if archiveFile, createErr = os.Create(partialPath); createErr != nil { // Failed to create our archive
+		return
+	}
+
+	createErr = WriteArchive(archiveFile, dir, format)
+
+	if closeErr := archiveFile.Close(); createErr == nil {
+		createErr = closeErr
+	}
+
+	if createErr == nil { // Complete
+		createErr = os.Rename(partialPath, archivePath)
+	} else {
+		os.Remove(partialPath)
+	}
+
+	return
+}
+
+// WriteArchive will write an archive in the provided format of the contents of dir, which is the same for the same contents
+func WriteArchive(w io.Writer, dir, format string) (writeErr error) {
+	var files []string
+	if files, writeErr = GetArchiveFiles(dir); writeErr != nil { // Failed to get our files
+		return
+	}
+
+	epoch := GetSourceDateEpoch()
+
+	if format == "zip" {
+		writeErr = WriteZip(w, dir, files, epoch)
+	} else {
+		writeErr = WriteCompressedTar(w, dir, files, format, epoch)
+	}
+
+	return
+}
+
+// WriteCompressedTar will write a tarball of the provided files within dir, compressed with the provided compressor
+func WriteCompressedTar(w io.Writer, dir string, files []string, compressor string, epoch time.Time) (writeErr error) {
+	var compressorWriter io.WriteCloser
+	if compressorWriter, writeErr = NewCompressor(w, compressor); writeErr != nil { // Unsupported or failed to set up our compressor
+		return
+	}
+
+	tarWriter := tar.NewWriter(compressorWriter)
+
+	for _, file := range files { // For each file or directory
+		if writeErr = WriteTarEntry(tarWriter, dir, file, epoch); writeErr != nil {
+			break
+		}
+	}
+
+	if closeErr := tarWriter.Close(); writeErr == nil { // Write the end of our tarball
+		writeErr = closeErr
+	}
+
+	if closeErr := compressorWriter.Close(); writeErr == nil { // Flush our compressor
+		writeErr = closeErr
+	}
+
+	return
+}
+
+// WriteTarEntry will write the provided file or directory within dir to the tarball, without any owner and with normalized permissions
+func WriteTarEntry(tarWriter *tar.Writer, dir, file string, epoch time.Time) (writeErr error) {
+	path := filepath.Join(dir, filepath.FromSlash(file))
+
+	var info os.FileInfo
+	if info, writeErr = os.Lstat(path); writeErr != nil {
+		return
+	}
+
+	header := &tar.Header{
+		Mode:     int64(GetArchiveMode(info)),
+		ModTime:  GetArchiveModTime(info, epoch),
+		Name:     file,
+		Typeflag: tar.TypeReg,
+	}
+
+	if info.IsDir() {
+		header.Name += "/"
+		header.Typeflag = tar.TypeDir
+	} else if info.Mode().IsRegular() {
+		header.Size = info.Size()
+	} else { // Such as a symlink
+		writeErr = errors.New("failed to archive " + file + ": not a regular file or directory")
+		return
+	}
+
+	if writeErr = tarWriter.WriteHeader(header); writeErr != nil || info.IsDir() { // Failed, or there is no content to write
+		return
+	}
+
+	writeErr = CopyFileTo(tarWriter, path)
+	return
+}
+
+// WriteZip will write a zip of the provided files within dir, with normalized permissions
+func WriteZip(w io.Writer, dir string, files []string, epoch time.Time) (writeErr error) {
+	zipWriter := zip.NewWriter(w)
+
+	for _, file := range files { // For each file or directory
+		path := filepath.Join(dir, filepath.FromSlash(file))
+
+		var info os.FileInfo
+		if info, writeErr = os.Lstat(path); writeErr != nil {
+			break
+		}
+
+		header := &zip.FileHeader{
+			Method:   zip.Deflate,
+			Modified: GetArchiveModTime(info, epoch),
+			Name:     file,
+		}
+
+		if header.Modified.Before(zipMinimumModTime) { // Can't be represented
+			header.Modified = zipMinimumModTime
+		}
+
+		if info.IsDir() {
+			header.Method = zip.Store
+			header.Name += "/"
+			header.SetMode(os.ModeDir | GetArchiveMode(info))
+		} else if info.Mode().IsRegular() {
+			header.SetMode(GetArchiveMode(info))
+		} else { // Such as a symlink
+			writeErr = errors.New("failed to archive " + file + ": not a regular file or directory")
+			break
+		}
+
+		var entryWriter io.Writer
+		if entryWriter, writeErr = zipWriter.CreateHeader(header); writeErr != nil {
+			break
+		}
+
+		if !info.IsDir() { // Has content
+			if writeErr = CopyFileTo(entryWriter, path); writeErr != nil {
+				break
+			}
+		}
+	}
+
+	if closeErr := zipWriter.Close(); writeErr == nil { // Write our central directory
+		writeErr = closeErr
+	}
+
+	return
+}
+
+// CopyFileTo will copy the contents of the file at the provided path to the writer
+func CopyFileTo(w io.Writer, path string) (copyErr error) {
+	var file *os.File
+	if file, copyErr = os.Open(path); copyErr != nil {
+		return
+	}
+
+	defer file.Close()
+
+	_, copyErr = io.Copy(w, file)
+	return
+}
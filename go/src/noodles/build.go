@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"github.com/JoshStrobl/trunk"
 	"github.com/spf13/cobra"
+	"sort"
+	"time"
 )
 
 var buildCmd = &cobra.Command{
@@ -16,42 +18,74 @@ var buildCmd = &cobra.Command{
 	DisableAutoGenTag: true,
 }
 
+var buildIncremental bool
 var buildProject string
+var buildWatch bool
 var debug bool
 
+// WatchInterval is how often we check watched projects for changes
+const WatchInterval = time.Second
+
 func init() {
+	buildCmd.Flags().BoolVarP(&buildIncremental, "incremental", "i", false, "Skip projects which are unchanged since they were last built, where the plugin supports it")
 	buildCmd.Flags().StringVarP(&buildProject, "project", "p", "", "Name of a project we're building")
+	buildCmd.Flags().BoolVarP(&buildWatch, "watch", "w", false, "Keep running, rebuilding projects when the files they use change, where the plugin supports it")
 	buildCmd.Flags().BoolVarP(&debug, "debug", "d", false, "Enable Debug Mode")
 }
 
 func build(cmd *cobra.Command, args []string) {
+	names := []string{}
+
 	if buildProject == "" { // If no project is set
 		for name := range noodles.Projects { // For each project
-			BuildProject(name)
+			names = append(names, name)
 		}
 	} else { // If a specific project is set
-		BuildProject(buildProject)
+		names = append(names, buildProject)
+	}
+
+	for _, name := range names {
+		BuildProject(name)
+	}
+
+	if buildWatch { // Keep rebuilding as files change
+		WatchProjects(names)
 	}
 }
 
+// GetPlugin will return the plugin with the provided name, or nil if there is no such plugin
+func GetPlugin(pluginName string) (plugin NoodlesPlugin) {
+	if pluginName == "go" {
+		plugin = &goPlugin
+	} else if pluginName == "less" {
+		plugin = &lessPlugin
+	} else if pluginName == "typescript" {
+		plugin = &typescriptPlugin
+	}
+
+	return
+}
+
 // BuildProject is responsible for determining the appropriate plugin to execute and handle requires.
 func BuildProject(name string) {
 	if project, exists := noodles.Projects[name]; exists { // If this project exists
-		RunRequires("RequiresPreRun", project.Requires)
-
-		var plugin NoodlesPlugin
+		plugin := GetPlugin(project.Plugin)
 
-		if project.Plugin == "go" {
-			plugin = &goPlugin
-		} else if project.Plugin == "less" {
-			plugin = &lessPlugin
-		} else if project.Plugin == "typescript" {
-			plugin = &typescriptPlugin
-		} else {
+		if plugin == nil {
 			trunk.LogErr("Failed to get the plugin for type: " + project.Plugin)
 			return
 		}
 
+		if incrementalPlugin, isIncremental := plugin.(NoodlesIncrementalPlugin); (buildIncremental || buildWatch) && isIncremental && incrementalPlugin.IsUpToDate(&project) { // Nothing has changed
+			trunk.LogInfo(name + " is up to date")
+			return
+		}
+
+		if requiresErr := RunRequires("RequiresPreRun", project.Requires); requiresErr != nil { // A script we require failed
+			trunk.LogErrRaw(fmt.Errorf("Skipped %s as a script it requires failed:\n%s\n", name, requiresErr.Error()))
+			return
+		}
+
 		trunk.LogInfo("Performing pre-run checks for " + name)
 		preRunErr := plugin.PreRun(&project)
 
@@ -73,7 +107,9 @@ func BuildProject(name string) {
 			}
 		}
 
-		RunRequires("RequiresPostRun", project.Requires)
+		if requiresErr := RunRequires("RequiresPostRun", project.Requires); requiresErr != nil {
+			trunk.LogErrRaw(fmt.Errorf("An error occurred running the scripts required after %s:\n%s\n", name, requiresErr.Error()))
+		}
 
 		trunk.LogInfo("Performing post-run for " + name)
 		postRunErr := plugin.PostRun(&project)
@@ -85,3 +121,34 @@ func BuildProject(name string) {
 		trunk.LogErr(name + " is not a valid project")
 	}
 }
+
+// WatchProjects will rebuild any of the provided projects which are no longer up to date, until we're interrupted
+func WatchProjects(names []string) {
+	watched := []string{}
+
+	for _, name := range names { // For each project we built
+		if _, isIncremental := GetPlugin(noodles.Projects[name].Plugin).(NoodlesIncrementalPlugin); isIncremental {
+			watched = append(watched, name)
+		} else {
+			trunk.LogWarn(name + " uses a plugin which does not support watching, so it will not be rebuilt")
+		}
+	}
+
+	if len(watched) == 0 { // Nothing to watch
+		return
+	}
+
+	sort.Strings(watched)
+	trunk.LogInfo("Watching for changes. Press Ctrl+C to stop.")
+
+	for range time.Tick(WatchInterval) {
+		for _, name := range watched { // For each watched project
+			project := noodles.Projects[name]
+
+			if !GetPlugin(project.Plugin).(NoodlesIncrementalPlugin).IsUpToDate(&project) { // Changed since it was last built
+				trunk.LogInfo("Changes detected in " + name)
+				BuildProject(name)
+			}
+		}
+	}
+}
@@ -0,0 +1,597 @@
+package main
+
+// This file contains a bzip2 compressor, since the standard library only provides a bzip2 decompressor
+// It chooses between multiple Huffman tables the same way bzip2 itself does, so our archives are within a fraction of a percent of bzip2 -9
+// The trade-off is speed, as our suffix sorting is several times slower than that of bzip2, which is noticeable when packing large Go binaries
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+)
+
+// Bzip2Writer is an io.WriteCloser which compresses everything written to it with bzip2
+type Bzip2Writer struct {
+	block       []byte // Current block, after the initial run-length encoding
+	blockCRC    uint32
+	closed      bool
+	combinedCRC uint32
+	level       int
+	maxBlock    int
+	out         bzip2BitWriter
+	runByte     byte
+	runLength   int
+	wroteHeader bool
+}
+
+type bzip2BitWriter struct {
+	bits  uint64
+	count uint
+	err   error
+	w     *bufio.Writer
+}
+
+const bzip2GroupSize = 50 // Symbols coded with the same Huffman table
+
+var bzip2CRCTable [256]uint32
+
+func init() {
+	for i := range bzip2CRCTable { // bzip2 uses the big-endian form of CRC-32
+		crc := uint32(i) << 24
+
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = (crc << 1) ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+
+		bzip2CRCTable[i] = crc
+	}
+}
+
+// NewBzip2Writer will return a Bzip2Writer at the provided level, being the block size in 100k from 1 to 9
+func NewBzip2Writer(w io.Writer, level int) *Bzip2Writer {
+	if level < 1 || level > 9 { // Invalid level, use the default of bzip2 itself
+		level = 9
+	}
+
+	return &Bzip2Writer{
+		blockCRC: 0xffffffff,
+		level:    level,
+		maxBlock: level*100000 - 19, // Same headroom as bzip2 itself
+		out:      bzip2BitWriter{w: bufio.NewWriter(w)},
+	}
+}
+
+// Write will compress the provided bytes, writing each block as it fills
+func (z *Bzip2Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, errors.New("bzip2: write to closed writer")
+	}
+
+	for _, b := range p {
+		if z.runLength != 0 && (b != z.runByte || z.runLength == 255) { // Run ended
+			z.flushRun()
+		}
+
+		z.runByte = b
+		z.runLength++
+	}
+
+	return len(p), z.out.err
+}
+
+// Close will write any remaining data and the end of the stream, without closing the underlying writer
+func (z *Bzip2Writer) Close() error {
+	if z.closed {
+		return z.out.err
+	}
+
+	z.closed = true
+
+	if z.runLength != 0 {
+		z.flushRun()
+	}
+
+	if len(z.block) != 0 || !z.wroteHeader { // Remaining data, or an empty stream which still needs its header
+		z.writeBlock()
+	}
+
+	z.out.write(0x177245, 24) // End of stream magic
+	z.out.write(0x385090, 24)
+	z.out.write(uint64(z.combinedCRC), 32)
+	z.out.flush()
+
+	return z.out.err
+}
+
+// flushRun will add the current run to our block using bzip2's initial run-length encoding
+func (z *Bzip2Writer) flushRun() {
+	if len(z.block)+5 > z.maxBlock { // Block is full
+		z.writeBlock()
+	}
+
+	for i := 0; i < z.runLength; i++ {
+		z.blockCRC = (z.blockCRC << 8) ^ bzip2CRCTable[byte(z.blockCRC>>24)^z.runByte]
+	}
+
+	if z.runLength < 4 { // Short runs are written as is
+		for i := 0; i < z.runLength; i++ {
+			z.block = append(z.block, z.runByte)
+		}
+	} else { // Four bytes followed by the number of additional repeats
+		z.block = append(z.block, z.runByte, z.runByte, z.runByte, z.runByte, byte(z.runLength-4))
+	}
+
+	z.runLength = 0
+}
+
+// writeBlock will compress and write our current block
+func (z *Bzip2Writer) writeBlock() {
+	if !z.wroteHeader { // Stream header, such as BZh9
+		z.out.write(uint64('B')<<24|uint64('Z')<<16|uint64('h')<<8|uint64('0'+z.level), 32)
+		z.wroteHeader = true
+	}
+
+	if len(z.block) == 0 { // Empty streams have no blocks
+		return
+	}
+
+	crc := ^z.blockCRC
+	z.combinedCRC = (z.combinedCRC<<1 | z.combinedCRC>>31) ^ crc
+
+	last, origPtr := bzip2BWT(z.block)
+	symbols, alphaSize, inUse := bzip2MTF(last)
+	tables, selectors := bzip2Tables(symbols, alphaSize)
+
+	z.out.write(0x314159, 24) // Block magic
+	z.out.write(0x265359, 24)
+	z.out.write(uint64(crc), 32)
+	z.out.write(0, 1) // Not randomized
+	z.out.write(uint64(origPtr), 24)
+
+	var usedRanges uint64
+
+	for i := 0; i < 16; i++ { // Which ranges of 16 bytes are used
+		for j := 0; j < 16; j++ {
+			if inUse[i*16+j] {
+				usedRanges |= 1 << uint(15-i)
+				break
+			}
+		}
+	}
+
+	z.out.write(usedRanges, 16)
+
+	for i := 0; i < 16; i++ { // Which bytes within each used range are used
+		if usedRanges&(1<<uint(15-i)) != 0 {
+			var used uint64
+
+			for j := 0; j < 16; j++ {
+				if inUse[i*16+j] {
+					used |= 1 << uint(15-j)
+				}
+			}
+
+			z.out.write(used, 16)
+		}
+	}
+
+	z.out.write(uint64(len(tables)), 3)
+	z.out.write(uint64(len(selectors)), 15)
+
+	order := make([]int, len(tables))
+
+	for i := range order {
+		order[i] = i
+	}
+
+	for _, selector := range selectors { // Move-to-front encoded, in unary
+		position := 0
+
+		for order[position] != selector {
+			position++
+		}
+
+		copy(order[1:position+1], order[:position])
+		order[0] = selector
+
+		for i := 0; i < position; i++ {
+			z.out.write(1, 1)
+		}
+
+		z.out.write(0, 1)
+	}
+
+	codes := make([][]uint32, len(tables))
+
+	for table, lengths := range tables { // Delta encoded code lengths
+		codes[table] = bzip2Codes(lengths)
+		current := lengths[0]
+		z.out.write(uint64(current), 5)
+
+		for _, length := range lengths {
+			for current < length {
+				z.out.write(2, 2) // Increment
+				current++
+			}
+
+			for current > length {
+				z.out.write(3, 2) // Decrement
+				current--
+			}
+
+			z.out.write(0, 1)
+		}
+	}
+
+	for i, symbol := range symbols { // Each group of 50 symbols uses the table of its selector
+		table := selectors[i/bzip2GroupSize]
+		z.out.write(uint64(codes[table][symbol]), uint(tables[table][symbol]))
+	}
+
+	z.block = z.block[:0]
+	z.blockCRC = 0xffffffff
+}
+
+// bzip2BWT will return the last column of the sorted rotations of the block, and the row of the block itself
+func bzip2BWT(block []byte) (last []byte, origPtr int) {
+	n := len(block)
+	sa := make([]int, n)
+	rank := make([]int, n)
+	tmp := make([]int, n)
+
+	for i := range sa {
+		sa[i] = i
+	}
+
+	sort.SliceStable(sa, func(a, b int) bool { return block[sa[a]] < block[sa[b]] })
+
+	for i := 1; i < n; i++ {
+		rank[sa[i]] = rank[sa[i-1]]
+
+		if block[sa[i]] != block[sa[i-1]] {
+			rank[sa[i]]++
+		}
+	}
+
+	count := make([]int, n+1)
+
+	for k := 1; k < n && rank[sa[n-1]] < n-1; k *= 2 { // Double the length of the sorted prefixes until every rotation is distinct
+		for i := range sa { // Sorted by the second half
+			tmp[i] = (sa[i] - k + n) % n
+		}
+
+		for i := range count {
+			count[i] = 0
+		}
+
+		for _, index := range tmp { // Stable counting sort by the first half
+			count[rank[index]+1]++
+		}
+
+		for i := 1; i <= n; i++ {
+			count[i] += count[i-1]
+		}
+
+		for _, index := range tmp {
+			sa[count[rank[index]]] = index
+			count[rank[index]]++
+		}
+
+		newRank := tmp // No longer needed
+		newRank[sa[0]] = 0
+
+		for i := 1; i < n; i++ {
+			newRank[sa[i]] = newRank[sa[i-1]]
+
+			if rank[sa[i]] != rank[sa[i-1]] || rank[(sa[i]+k)%n] != rank[(sa[i-1]+k)%n] {
+				newRank[sa[i]]++
+			}
+		}
+
+		rank, tmp = newRank, rank
+	}
+
+	last = make([]byte, n)
+
+	for i, start := range sa {
+		last[i] = block[(start+n-1)%n]
+
+		if start == 0 {
+			origPtr = i
+		}
+	}
+
+	return
+}
+
+// bzip2MTF will move-to-front encode the last column, with runs of zeros encoded as RUNA and RUNB, ending with the end of block symbol
+func bzip2MTF(last []byte) (symbols []uint16, alphaSize int, inUse [256]bool) {
+	var toIndex [256]byte
+	var numInUse int
+
+	for _, b := range last {
+		inUse[b] = true
+	}
+
+	for b := 0; b < 256; b++ {
+		if inUse[b] {
+			toIndex[b] = byte(numInUse)
+			numInUse++
+		}
+	}
+
+	order := make([]byte, numInUse)
+
+	for i := range order {
+		order[i] = byte(i)
+	}
+
+	zeros := 0
+	flushZeros := func() {
+		for zeros > 0 { // Bijective base 2, where RUNA is 1 and RUNB is 2
+			zeros--
+			symbols = append(symbols, uint16(zeros&1))
+			zeros >>= 1
+		}
+	}
+
+	for _, b := range last {
+		index := toIndex[b]
+		position := 0
+
+		for order[position] != index {
+			position++
+		}
+
+		if position == 0 {
+			zeros++
+			continue
+		}
+
+		flushZeros()
+		copy(order[1:position+1], order[:position])
+		order[0] = index
+		symbols = append(symbols, uint16(position+1))
+	}
+
+	flushZeros()
+	alphaSize = numInUse + 2
+	symbols = append(symbols, uint16(numInUse+1)) // End of block
+
+	return
+}
+
+// bzip2Tables will return the Huffman tables for the symbols and which table each group of 50 symbols uses, chosen the same way as bzip2 itself
+// Starting with tables which each favour a range of the alphabet, every group picks its cheapest table and each table is then rebuilt from the groups using it
+func bzip2Tables(symbols []uint16, alphaSize int) (tables [][]int, selectors []int) {
+	numTables := 6
+
+	switch { // Fewer tables for fewer symbols, since every table costs bits to send
+	case len(symbols) < 200:
+		numTables = 2
+	case len(symbols) < 600:
+		numTables = 3
+	case len(symbols) < 1200:
+		numTables = 4
+	case len(symbols) < 2400:
+		numTables = 5
+	}
+
+	frequencies := make([]int, alphaSize)
+
+	for _, symbol := range symbols {
+		frequencies[symbol]++
+	}
+
+	tables = make([][]int, numTables)
+	remaining := len(symbols)
+	start := 0
+
+	for part := numTables; part > 0; part-- { // Split the alphabet into ranges of roughly equal frequency
+		target := remaining / part
+		end := start - 1
+		total := 0
+
+		for total < target && end < alphaSize-1 {
+			end++
+			total += frequencies[end]
+		}
+
+		if end > start && part != numTables && part != 1 && (numTables-part)%2 == 1 { // Alternate rounding down, as bzip2 does
+			total -= frequencies[end]
+			end--
+		}
+
+		lengths := make([]int, alphaSize)
+
+		for symbol := range lengths { // Symbols outside of the range are expensive
+			if symbol < start || symbol > end {
+				lengths[symbol] = 15
+			}
+		}
+
+		tables[part-1] = lengths
+		start = end + 1
+		remaining -= total
+	}
+
+	selectors = make([]int, (len(symbols)+bzip2GroupSize-1)/bzip2GroupSize)
+
+	for iteration := 0; iteration < 4; iteration++ {
+		tableFrequencies := make([][]int, numTables)
+
+		for table := range tableFrequencies {
+			tableFrequencies[table] = make([]int, alphaSize)
+		}
+
+		for group := range selectors { // Use the table which codes the group in the fewest bits
+			groupSymbols := symbols[group*bzip2GroupSize:]
+
+			if len(groupSymbols) > bzip2GroupSize {
+				groupSymbols = groupSymbols[:bzip2GroupSize]
+			}
+
+			best, bestCost := 0, -1
+
+			for table, lengths := range tables {
+				cost := 0
+
+				for _, symbol := range groupSymbols {
+					cost += lengths[symbol]
+				}
+
+				if bestCost == -1 || cost < bestCost {
+					best, bestCost = table, cost
+				}
+			}
+
+			selectors[group] = best
+
+			for _, symbol := range groupSymbols {
+				tableFrequencies[best][symbol]++
+			}
+		}
+
+		for table := range tables {
+			tables[table] = bzip2CodeLengths(tableFrequencies[table])
+		}
+	}
+
+	return
+}
+
+// bzip2CodeLengths will return Huffman code lengths for every symbol in the alphabet, limited to the 17 bits bzip2 itself uses
+func bzip2CodeLengths(frequencies []int) []int {
+	weights := make([]int, len(frequencies))
+
+	for i, frequency := range frequencies { // Every symbol needs a code
+		weights[i] = frequency
+
+		if weights[i] == 0 {
+			weights[i] = 1
+		}
+	}
+
+	for {
+		lengths := bzip2HuffmanLengths(weights)
+		longest := 0
+
+		for _, length := range lengths {
+			if length > longest {
+				longest = length
+			}
+		}
+
+		if longest <= 17 {
+			return lengths
+		}
+
+		for i := range weights { // Flatten our weights and try again
+			weights[i] = weights[i]/2 + 1
+		}
+	}
+}
+
+// bzip2HuffmanLengths will return the Huffman code length of each of the provided frequencies
+func bzip2HuffmanLengths(frequencies []int) []int {
+	n := len(frequencies)
+	leaves := make([]int, n) // Symbols, least frequent first
+
+	for i := range leaves {
+		leaves[i] = i
+	}
+
+	sort.SliceStable(leaves, func(a, b int) bool { return frequencies[leaves[a]] < frequencies[leaves[b]] })
+
+	weights := make([]int, n, 2*n-1) // Our leaves, followed by each merged node in the order they were merged
+	parents := make([]int, 2*n-1)
+
+	for i, symbol := range leaves {
+		weights[i] = frequencies[symbol]
+	}
+
+	nextLeaf, nextMerged := 0, n
+	leastFrequent := func() int { // Merged nodes are created in order of weight, so the least frequent node is at the front of either queue
+		if nextLeaf < n && (nextMerged == len(weights) || weights[nextLeaf] <= weights[nextMerged]) {
+			nextLeaf++
+			return nextLeaf - 1
+		}
+
+		nextMerged++
+		return nextMerged - 1
+	}
+
+	for len(weights) < 2*n-1 { // Merge our two least frequent nodes
+		a, b := leastFrequent(), leastFrequent()
+		parents[a], parents[b] = len(weights), len(weights)
+		weights = append(weights, weights[a]+weights[b])
+	}
+
+	depths := make([]int, 2*n-1) // The root is the last node, with a depth of zero
+
+	for node := 2*n - 3; node >= 0; node-- { // Parents always come after their children
+		depths[node] = depths[parents[node]] + 1
+	}
+
+	lengths := make([]int, n)
+
+	for i, symbol := range leaves {
+		lengths[symbol] = depths[i]
+	}
+
+	return lengths
+}
+
+// bzip2Codes will return the canonical Huffman codes for the provided code lengths
+func bzip2Codes(lengths []int) []uint32 {
+	codes := make([]uint32, len(lengths))
+	var code uint32
+
+	for length := 1; length <= 20; length++ { // Codes are assigned by length, then by symbol
+		for symbol, symbolLength := range lengths {
+			if symbolLength == length {
+				codes[symbol] = code
+				code++
+			}
+		}
+
+		code <<= 1
+	}
+
+	return codes
+}
+
+// write will write the lowest count bits of value, most significant first
+func (b *bzip2BitWriter) write(value uint64, count uint) {
+	b.bits = b.bits<<count | value&(1<<count-1)
+	b.count += count
+
+	for b.count >= 8 {
+		b.count -= 8
+		b.emit(byte(b.bits >> b.count))
+	}
+}
+
+// flush will write any remaining bits, padded with zeros to a whole byte, to the underlying writer
+func (b *bzip2BitWriter) flush() {
+	if b.count != 0 {
+		b.emit(byte(b.bits << (8 - b.count)))
+		b.count = 0
+	}
+
+	if b.err == nil {
+		b.err = b.w.Flush()
+	}
+}
+
+// emit will write a byte to the underlying writer, remembering the first error
+func (b *bzip2BitWriter) emit(c byte) {
+	if b.err == nil {
+		b.err = b.w.WriteByte(c)
+	}
+}
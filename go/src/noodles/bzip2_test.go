@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestBzip2WriterRoundTrip will compress each input with our Bzip2Writer and check the standard library decompresses it back to the same bytes
+func TestBzip2WriterRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	randomData := make([]byte, 300000)
+	random.Read(randomData)
+
+	text := []byte(strings.Repeat("noodles is an opinionated manager for web applications. ", 20000)) // Over 1MB, so more than one 900k block
+
+	mixed := []byte{}
+
+	for len(mixed) < 1200000 { // Runs of random lengths, either side of the 4 byte run-length encoding threshold and 255 byte run limit
+		mixed = append(mixed, bytes.Repeat([]byte{byte(random.Intn(4))}, 1+random.Intn(600))...)
+	}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		level int
+	}{
+		{"empty", []byte{}, 9},
+		{"single byte", []byte{'a'}, 9},
+		{"run of 4", bytes.Repeat([]byte{'a'}, 4), 9},
+		{"run of 5", bytes.Repeat([]byte{'a'}, 5), 9},
+		{"run of 255", bytes.Repeat([]byte{'a'}, 255), 9},
+		{"run of 256", bytes.Repeat([]byte{'a'}, 256), 9},
+		{"run longer than 255", bytes.Repeat([]byte{'a'}, 100000), 9},
+		{"every byte value", func() []byte {
+			all := make([]byte, 256)
+			for i := range all {
+				all[i] = byte(i)
+			}
+			return all
+		}(), 9},
+		{"random", randomData, 9},
+		{"random in 100k blocks", randomData, 1},
+		{"larger than one 900k block", text, 9},
+		{"mixed runs", mixed, 9},
+		{"mixed runs in 100k blocks", mixed, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var compressed bytes.Buffer
+			writer := NewBzip2Writer(&compressed, test.level)
+
+			for offset := 0; offset < len(test.data); offset += 65536 { // Write in chunks, as io.Copy would
+				end := offset + 65536
+
+				if end > len(test.data) {
+					end = len(test.data)
+				}
+
+				if _, writeErr := writer.Write(test.data[offset:end]); writeErr != nil {
+					t.Fatalf("Write failed: %s", writeErr)
+				}
+			}
+
+			if closeErr := writer.Close(); closeErr != nil {
+				t.Fatalf("Close failed: %s", closeErr)
+			}
+
+			decompressed, readErr := ioutil.ReadAll(bzip2.NewReader(&compressed))
+
+			if readErr != nil {
+				t.Fatalf("Failed to decompress: %s", readErr)
+			}
+
+			if !bytes.Equal(decompressed, test.data) {
+				t.Fatalf("Decompressed %d bytes, which do not match the %d bytes compressed", len(decompressed), len(test.data))
+			}
+		})
+	}
+}
+
+// TestBzip2WriterClosed will check writing after Close fails rather than corrupting the stream
+func TestBzip2WriterClosed(t *testing.T) {
+	writer := NewBzip2Writer(&bytes.Buffer{}, 9)
+	writer.Close()
+
+	if _, writeErr := writer.Write([]byte("noodles")); writeErr == nil {
+		t.Fatal("Write after Close succeeded")
+	}
+}
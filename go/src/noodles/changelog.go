@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JoshStrobl/trunk"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var changelogCmd = &cobra.Command{
+	Use:               "changelog",
+	Short:             "Generate release notes from git history",
+	Long:              "Generate Markdown release notes from the git log between version tags, grouped by conventional commit type or by the projects whose Source paths each commit touched",
+	Run:               changelog,
+	DisableAutoGenTag: true,
+}
+
+// ChangelogCommit is a commit listed in our changelog
+type ChangelogCommit struct {
+	Breaking    bool   // Has a ! after its type, or a BREAKING CHANGE footer
+	Description string // Subject without its type and scope, or the whole subject if it isn't a conventional commit
+	Files       []string
+	Hash        string
+	Scope       string
+	Subject     string
+	Type        string
+}
+
+// ChangelogRelease is the commits of a version, or those not yet released
+type ChangelogRelease struct {
+	Commits []ChangelogCommit
+	Date    string
+	Version string // Version of its tag, our untagged Version, or Unreleased
+}
+
+// ChangelogTag is a git tag of a semantic version
+type ChangelogTag struct {
+	Name    string
+	Version SemanticVersion
+}
+
+// changelogTypes are the headings of conventional commit types, in the order they are rendered
+var changelogTypes = [][2]string{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance"},
+	{"refactor", "Refactoring"},
+	{"docs", "Documentation"},
+	{"test", "Tests"},
+	{"build", "Build"},
+	{"ci", "Continuous Integration"},
+	{"style", "Style"},
+	{"chore", "Chores"},
+	{"revert", "Reverts"},
+}
+
+// conventionalCommitRegex matches the subject of a conventional commit, such as feat(pack)!: add zip
+var conventionalCommitRegex = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+
+var changelogFrom string
+var changelogGroup string
+var changelogLatest bool
+var changelogOutput string
+var changelogTo string
+
+func init() {
+	changelogCmd.Flags().StringVarP(&changelogFrom, "from", "", "", "Tag or commit to list changes after, rather than every release")
+	changelogCmd.Flags().StringVarP(&changelogGroup, "group", "g", "", "Group commits by type or project, regardless of Distribution ChangelogGroup")
+	changelogCmd.Flags().BoolVarP(&changelogLatest, "latest", "l", false, "Only list the changes of the latest release, or those since it if there are any")
+	changelogCmd.Flags().StringVarP(&changelogOutput, "output", "o", "", "File to write the changelog to, rather than stdout")
+	changelogCmd.Flags().StringVarP(&changelogTo, "to", "", "HEAD", "Tag or commit to list changes up to")
+}
+
+func changelog(cmd *cobra.Command, args []string) {
+	group := changelogGroup
+
+	if group == "" {
+		group = GetChangelogGroup()
+	}
+
+	if !ListContainsExact(SupportedChangelogGroups, group) {
+		trunk.LogFatal("Changelogs can only be grouped by: " + strings.Join(SupportedChangelogGroups, ","))
+	}
+
+	releases, releasesErr := GetChangelogReleases(changelogFrom, changelogTo, changelogLatest)
+
+	if releasesErr != nil {
+		trunk.LogErrRaw(fmt.Errorf("Failed to read the git history:\n%s", releasesErr.Error()))
+		os.Exit(1)
+	}
+
+	markdown := RenderChangelog(releases, group, changelogFrom == "" && !changelogLatest)
+
+	if changelogOutput == "" {
+		fmt.Print(markdown)
+	} else if writeErr := ioutil.WriteFile(changelogOutput, []byte(markdown), 0644); writeErr != nil {
+		trunk.LogErrRaw(fmt.Errorf("Failed to write %s:\n%s", changelogOutput, writeErr.Error()))
+		os.Exit(1)
+	}
+}
+
+// GetChangelogGroup will return how our packed changelog groups commits, from Distribution ChangelogGroup or by type
+func GetChangelogGroup() string {
+	if noodles.Distribution.ChangelogGroup != "" {
+		return noodles.Distribution.ChangelogGroup
+	}
+
+	return "type"
+}
+
+// GetGitOutput will run git with the provided args in our workdir, returning its output
+func GetGitOutput(args ...string) (output string, gitErr error) {
+	gitCmd := exec.Command("git", args...)
+	gitCmd.Dir = workdir
+
+	var outputBytes []byte
+	if outputBytes, gitErr = gitCmd.Output(); gitErr != nil {
+		if exitErr, isExitErr := gitErr.(*exec.ExitError); isExitErr && len(exitErr.Stderr) != 0 { // Use git's own message
+			gitErr = errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+		}
+
+		return
+	}
+
+	output = string(outputBytes)
+	return
+}
+
+// GetVersionTags will return the tags of semantic versions reachable from the provided commit, latest version first
+func GetVersionTags(to string) (tags []ChangelogTag, tagsErr error) {
+	var output string
+	if output, tagsErr = GetGitOutput("tag", "--merged", to); tagsErr != nil {
+		return
+	}
+
+	for _, name := range strings.Fields(output) {
+		if version, parseErr := ParseSemanticVersion(name); parseErr == nil { // Tag of a version, such as v1.2.0
+			tags = append(tags, ChangelogTag{Name: name, Version: version})
+		}
+	}
+
+	sort.SliceStable(tags, func(a, b int) bool { return tags[a].Version.Compare(tags[b].Version) > 0 })
+	return
+}
+
+// GetChangelogReleases will return the releases between the provided commits, or every release up to the provided commit if from is empty
+// Changes after the latest tag are listed under our Version if it hasn't been tagged yet, otherwise as Unreleased
+// If latest, we only return the changes since the latest tag or, if there are none, those of the latest tag itself
+func GetChangelogReleases(from, to string, latest bool) (releases []ChangelogRelease, releasesErr error) {
+	var tags []ChangelogTag
+	if tags, releasesErr = GetVersionTags(to); releasesErr != nil {
+		return
+	}
+
+	var toHash string
+	if toHash, releasesErr = GetGitOutput("rev-parse", to+"^{commit}"); releasesErr != nil {
+		return
+	}
+
+	unreleased := "Unreleased"
+
+	if len(tags) == 0 || noodles.Version.Compare(tags[0].Version) > 0 { // Version was bumped, but not yet tagged
+		unreleased = noodles.Version.String()
+	}
+
+	bounds := []string{to} // Commits each release ends at, latest first
+	versions := []string{unreleased}
+
+	for _, tag := range tags {
+		if tagHash, _ := GetGitOutput("rev-parse", tag.Name+"^{commit}"); len(bounds) == 1 && bounds[0] == to && tagHash == toHash { // We end at this tag, so nothing is unreleased
+			bounds, versions = []string{}, []string{}
+		}
+
+		bounds = append(bounds, tag.Name)
+		versions = append(versions, tag.Version.String())
+	}
+
+	if from != "" { // Only the changes after from, up to the release we end at
+		bounds, versions = bounds[:1], versions[:1]
+	}
+
+	for index, bound := range bounds {
+		start := from
+
+		if from == "" && index+1 < len(bounds) { // Changes since the previous release
+			start = bounds[index+1]
+		}
+
+		var release ChangelogRelease
+		if release, releasesErr = GetChangelogRelease(start, bound, versions[index]); releasesErr != nil {
+			return
+		}
+
+		if len(release.Commits) == 0 && bound == to && from == "" && len(bounds) > 1 { // Nothing since our latest tag
+			continue
+		}
+
+		releases = append(releases, release)
+
+		if latest { // Only a single release
+			break
+		}
+	}
+
+	return
+}
+
+// GetChangelogRelease will return the commits after start, up to and including end
+func GetChangelogRelease(start, end, version string) (release ChangelogRelease, releaseErr error) {
+	release.Version = version
+	revisions := end
+
+	if start != "" {
+		revisions = start + ".." + end
+	}
+
+	var date string
+	if date, releaseErr = GetGitOutput("log", "-1", "--format=%cd", "--date=short", end); releaseErr != nil {
+		return
+	}
+
+	release.Date = strings.TrimSpace(date)
+
+	var output string
+	if output, releaseErr = GetGitOutput("log", "--no-merges", "--relative", "--name-only", "--format=%x1e%H%x1f%s%x1f%b%x1f", revisions); releaseErr != nil {
+		return
+	}
+
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.Split(record, "\x1f")
+
+		if len(fields) != 4 { // Empty, before our first commit
+			continue
+		}
+
+		commit := ChangelogCommit{
+			Breaking:    strings.Contains(fields[2], "BREAKING CHANGE:") || strings.Contains(fields[2], "BREAKING-CHANGE:"),
+			Description: fields[1],
+			Files:       strings.Fields(fields[3]),
+			Hash:        fields[0],
+			Subject:     fields[1],
+		}
+
+		if matches := conventionalCommitRegex.FindStringSubmatch(fields[1]); matches != nil { // Conventional commit
+			commit.Breaking = commit.Breaking || matches[3] == "!"
+			commit.Description = matches[4]
+			commit.Scope = matches[2]
+			commit.Type = strings.ToLower(matches[1])
+		}
+
+		release.Commits = append(release.Commits, commit)
+	}
+
+	return
+}
+
+// GetCommitProjects will return the sorted names of the projects whose Source paths the commit touched
+func GetCommitProjects(commit ChangelogCommit) []string {
+	names := []string{}
+
+	for name, project := range noodles.Projects {
+		dirs := []string{}
+
+		for _, dir := range []string{project.SourceDir, project.ModuleDir} {
+			if dir = filepath.ToSlash(filepath.Clean(dir)); dir != "." && dir != "" { // Not our entire workspace
+				dirs = append(dirs, dir+"/")
+			}
+		}
+
+		for _, file := range commit.Files {
+			touched := file == filepath.ToSlash(project.Source)
+
+			for _, dir := range dirs {
+				touched = touched || strings.HasPrefix(file, dir)
+			}
+
+			if touched {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// RenderChangelog will render the releases as Markdown, grouping their commits by type or project
+func RenderChangelog(releases []ChangelogRelease, group string, withTitle bool) string {
+	var markdown string
+
+	if withTitle {
+		markdown += "# Changelog\n\n"
+	}
+
+	for index, release := range releases {
+		if index != 0 {
+			markdown += "\n"
+		}
+
+		markdown += "## " + release.Version + " (" + release.Date + ")\n"
+
+		if len(release.Commits) == 0 {
+			markdown += "\nNo changes.\n"
+			continue
+		}
+
+		headings := []string{}
+		sections := make(map[string][]string)
+
+		addEntry := func(heading, entry string) {
+			if _, exists := sections[heading]; !exists {
+				headings = append(headings, heading)
+			}
+
+			sections[heading] = append(sections[heading], entry)
+		}
+
+		if group == "project" {
+			for _, commit := range release.Commits {
+				projects := GetCommitProjects(commit)
+
+				if len(projects) == 0 { // Only touched the rest of our workspace
+					projects = []string{"Other Changes"}
+				}
+
+				for _, project := range projects {
+					addEntry(project, FormatChangelogEntry(commit.Subject, "", commit.Hash))
+				}
+			}
+
+			sort.SliceStable(headings, func(a, b int) bool { // Projects sorted, with everything else last
+				return headings[b] == "Other Changes" && headings[a] != "Other Changes" || headings[a] != "Other Changes" && headings[b] != "Other Changes" && headings[a] < headings[b]
+			})
+		} else {
+			order := []string{"Breaking Changes"}
+			typeHeadings := make(map[string]string)
+
+			for _, changelogType := range changelogTypes {
+				order = append(order, changelogType[1])
+				typeHeadings[changelogType[0]] = changelogType[1]
+			}
+
+			order = append(order, "Other Changes")
+
+			for _, commit := range release.Commits {
+				heading, exists := typeHeadings[commit.Type]
+
+				if commit.Breaking {
+					heading = "Breaking Changes"
+				} else if !exists { // Not a conventional commit, or a type we don't know
+					heading = "Other Changes"
+				}
+
+				addEntry(heading, FormatChangelogEntry(commit.Description, commit.Scope, commit.Hash))
+			}
+
+			headings = []string{}
+
+			for _, heading := range order {
+				if _, exists := sections[heading]; exists {
+					headings = append(headings, heading)
+				}
+			}
+		}
+
+		for _, heading := range headings {
+			markdown += "\n### " + heading + "\n\n" + strings.Join(sections[heading], "")
+		}
+	}
+
+	return markdown
+}
+
+// FormatChangelogEntry will return the list item of a commit, such as - **pack:** add zip (5114f85)
+func FormatChangelogEntry(description, scope, hash string) string {
+	if scope != "" {
+		description = "**" + scope + ":** " + description
+	}
+
+	if len(hash) > 7 { // Abbreviated, the same as git
+		hash = hash[:7]
+	}
+
+	return "- " + description + " (" + hash + ")\n"
+}
+
+// GetChangelogSourcePath will return the path our packed changelog is generated to
+func GetChangelogSourcePath() string {
+	return filepath.Join(workdir, ".noodles", "CHANGELOG.md")
+}
+
+// WriteChangelogFile will generate the changelog of every release to the path we pack it from
+func WriteChangelogFile() (writeErr error) {
+	var releases []ChangelogRelease
+	if releases, writeErr = GetChangelogReleases("", "HEAD", false); writeErr != nil {
+		return
+	}
+
+	if writeErr = os.MkdirAll(filepath.Dir(GetChangelogSourcePath()), 0755); writeErr == nil {
+		writeErr = ioutil.WriteFile(GetChangelogSourcePath(), []byte(RenderChangelog(releases, GetChangelogGroup(), true)), 0644)
+	}
+
+	return
+}
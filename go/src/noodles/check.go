@@ -27,6 +27,10 @@ func check(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	if noodles.Version.IsNumber { // Using the deprecated form
+		trunk.LogWarn("Version is written as a number, which is deprecated. Use noodles version bump, or write it as \"" + noodles.Version.String() + "\".\n")
+	}
+
 	for name, project := range noodles.Projects { // For each project
 		var plugin NoodlesPlugin
 		trunk.LogInfo("Checking " + name)
@@ -47,7 +51,7 @@ func check(cmd *cobra.Command, args []string) {
 			}
 
 			results := plugin.Check(&project) // Check the project, return our check results
-			resultsTypes := []string{"Deprecations", "Errors", "Recommendations"}
+			resultsTypes := []string{"Deprecations", "Errors", "Recommendations", "Warnings"}
 
 			for _, resultType := range resultsTypes {
 				if resultList, exists := results[resultType]; exists { // This type exists
@@ -60,6 +64,8 @@ func check(cmd *cobra.Command, args []string) {
 							trunk.LogErr(header)
 						} else if resultType == "Recommendations" { // Recommendations
 							trunk.LogInfo(header)
+						} else if resultType == "Warnings" { // Warnings
+							trunk.LogWarn(header)
 						}
 
 						for _, item := range resultList { // For each item
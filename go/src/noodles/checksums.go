@@ -0,0 +1,114 @@
+package main
+
+// This file contains functionality pertaining to the SHA256SUMS of our archives
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const ChecksumsFile = "SHA256SUMS" // Same name and format as sha256sum uses
+
+// GetFileSHA256 will return the hex encoded SHA-256 digest of the file at the provided path
+func GetFileSHA256(path string) (digest string, hashErr error) {
+	var file *os.File
+	if file, hashErr = os.Open(path); hashErr != nil {
+		return
+	}
+
+	defer file.Close()
+
+	hasher := sha256.New()
+
+	if _, hashErr = io.Copy(hasher, file); hashErr == nil {
+		digest = hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	return
+}
+
+// WriteChecksums will add the SHA256SUMS of the provided archives, which must all be in the same directory, to those alongside them
+// Checksums of archives from earlier runs are kept while those archives still exist, so packing some projects or versions doesn't drop the rest
+func WriteChecksums(archives []string) (writeErr error) {
+	if len(archives) == 0 { // Nothing to checksum
+		return
+	}
+
+	dir := filepath.Dir(archives[0])
+	checksumsPath := filepath.Join(dir, ChecksumsFile)
+	checksums := make(map[string]string)
+
+	if _, statErr := os.Stat(checksumsPath); statErr == nil { // Have checksums from earlier runs
+		var existing map[string]string
+		if existing, writeErr = ReadChecksums(checksumsPath); writeErr != nil {
+			return
+		}
+
+		for name, digest := range existing {
+			if _, statErr := os.Stat(filepath.Join(dir, name)); statErr == nil { // Still exists
+				checksums[name] = digest
+			}
+		}
+	}
+
+	for _, archive := range archives {
+		var digest string
+		if digest, writeErr = GetFileSHA256(archive); writeErr != nil { // Failed to hash the archive
+			return
+		}
+
+		checksums[filepath.Base(archive)] = digest
+	}
+
+	names := []string{}
+
+	for name := range checksums {
+		names = append(names, name)
+	}
+
+	sort.Strings(names) // Same order every time
+
+	var content string
+
+	for _, name := range names {
+		content += checksums[name] + "  " + name + "\n"
+	}
+
+	writeErr = ioutil.WriteFile(checksumsPath, []byte(content), 0644)
+	return
+}
+
+// ReadChecksums will return the digests of the SHA256SUMS at the provided path, by file name
+func ReadChecksums(path string) (checksums map[string]string, readErr error) {
+	var content []byte
+	if content, readErr = ioutil.ReadFile(path); readErr != nil {
+		return
+	}
+
+	checksums = make(map[string]string)
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line == "" { // Empty line
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 2)
+
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 { // Not a digest and file name
+			readErr = errors.New(path + " is not a valid " + ChecksumsFile + " file")
+			return
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(fields[1], " "), "*") // Text or binary mode of sha256sum
+		checksums[name] = strings.ToLower(fields[0])
+	}
+
+	return
+}
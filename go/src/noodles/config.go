@@ -12,25 +12,114 @@ import (
 
 // NoodlesConfig is the configuration of global properties of Noodles.
 type NoodlesConfig struct {
+	Browsers     []string `toml:"Browsers,omitempty"`
 	Description  string
 	Distribution *NoodlesDistributionConfig
 	License      string
 	Name         string
 	Projects     map[string]NoodlesProject
 	Scripts      map[string]NoodlesScript
-	Version      float64
+	Version      NoodlesVersion
 }
 
 // NoodlesDistributionConfig is the configuration for distribution
 type NoodlesDistributionConfig struct {
-	TarCompressors []string
+	Changelog          string                          `toml:"Changelog,omitempty"`      // Path within our archives of the changelog we generate from git history, such as CHANGELOG.md
+	ChangelogGroup     string                          `toml:"ChangelogGroup,omitempty"` // Group commits of our changelog by type or project
+	Exclude            []string                        `toml:"Exclude,omitempty"`        // Patterns of files we never pack, such as *.bak
+	Images             map[string]NoodlesImageConfig   `toml:"Images,omitempty"`         // OCI images of Go binary projects, by project name
+	Include            map[string]string               `toml:"Include,omitempty"`        // Patterns of files to pack, mapped to the directory within our archives
+	IncludeSourceMaps  bool                            `toml:"IncludeSourceMaps,omitempty"`
+	OutputDir          string                          `toml:"OutputDir,omitempty"` // Directory our archives are written to, relative to our workspace
+	Package            NoodlesPackageConfig            `toml:"Package,omitempty"`
+	PackageFormats     []string                        `toml:"PackageFormats,omitempty"`     // Packages we create alongside our archives, such as deb and rpm
+	ProjectArchives    bool                            `toml:"ProjectArchives,omitempty"`    // Also create an archive for each project
+	ProjectArchiveName string                          `toml:"ProjectArchiveName,omitempty"` // Name of project archives, such as {project}-{version}{platform}
+	Publish            map[string]NoodlesPublishTarget `toml:"Publish,omitempty"`            // Where publish uploads our artifacts, by target name
+	PublicKey          string                          `toml:"PublicKey,omitempty"`          // Path to the minisign public key archives are verified with
+	SigningKey         string                          `toml:"SigningKey,omitempty"`         // Path to the minisign secret key archives are signed with
+	TarCompressors     []string
 }
 
-var SupportedTarCompressors []string // SupportedTarCompressions are various compressions we officially support
-var noodles NoodlesConfig            // Our Noodles Config
+// NoodlesPublishTarget is somewhere publish uploads our artifacts to, being a directory, HTTP PUT endpoint or S3 compatible bucket
+type NoodlesPublishTarget struct {
+	Attempts      int               `toml:"Attempts,omitempty"` // Attempts at uploading each file before giving up, defaulting to 3
+	Bucket        string            `toml:"Bucket,omitempty"`   // Bucket of s3 targets
+	Endpoint      string            `toml:"Endpoint,omitempty"` // API of s3 targets, such as http://localhost:9000 for MinIO, defaulting to AWS
+	Headers       map[string]string `toml:"Headers,omitempty"`  // Headers of http targets, where ${VARIABLE} is replaced with the environment variable
+	Path          string            `toml:"Path,omitempty"`     // Directory of dir targets, or the prefix of keys within the bucket of s3 targets. May contain {version} and {workspace}
+	Region        string            `toml:"Region,omitempty"`   // Region of s3 targets, defaulting to us-east-1
+	Type          string            // One of dir, http or s3
+	URL           string            `toml:"URL,omitempty"`           // URL of http targets, which each file is PUT under. May contain {version} and {workspace}
+	VirtualHosted bool              `toml:"VirtualHosted,omitempty"` // Address the bucket of s3 targets as a subdomain of Endpoint, rather than within its path
+}
+
+// NoodlesImageConfig is the configuration of the OCI image we create of a Go binary project
+type NoodlesImageConfig struct {
+	BaseLayer    string            `toml:"BaseLayer,omitempty"`    // Path to a tarball, optionally gzipped, of the root filesystem we add our binary to. May contain {os} and {arch}
+	Cmd          []string          `toml:"Cmd,omitempty"`          // Default arguments to our Entrypoint
+	Entrypoint   []string          `toml:"Entrypoint,omitempty"`   // Defaults to our binary
+	Env          map[string]string `toml:"Env,omitempty"`          // Environment variables, where PATH has a default
+	ExposedPorts []string          `toml:"ExposedPorts,omitempty"` // Such as 8080/tcp
+	InstallPath  string            `toml:"InstallPath,omitempty"`  // Path of our binary within the image, defaulting to /usr/local/bin/<project>
+	Labels       map[string]string `toml:"Labels,omitempty"`       // Added to, or replacing, the labels built from our workspace
+	Layout       string            `toml:"Layout,omitempty"`       // Either dir or tar, defaulting to tar
+	Platforms    []string          `toml:"Platforms,omitempty"`    // Such as linux/arm64, defaulting to every build of the project we find
+	User         string            `toml:"User,omitempty"`
+	WorkingDir   string            `toml:"WorkingDir,omitempty"`
+}
+
+// NoodlesPackageConfig is the configuration of the .deb and .rpm packages we create of our workspace
+type NoodlesPackageConfig struct {
+	ConfigFiles  map[string]string `toml:"ConfigFiles,omitempty"` // Files, relative to our workspace, mapped to where they are installed and kept across upgrades
+	DebDepends   []string          `toml:"DebDepends,omitempty"`  // Dependencies only our .deb has, using Debian package names
+	Depends      []string          `toml:"Depends,omitempty"`     // Dependencies of every package, such as "libc6 >= 2.28"
+	Homepage     string            `toml:"Homepage,omitempty"`
+	InstallDir   string            `toml:"InstallDir,omitempty"`   // Where files without an InstallPaths pattern are installed, defaulting to /usr/share/<name>
+	InstallPaths map[string]string `toml:"InstallPaths,omitempty"` // Patterns of paths within our archives, mapped to the directory they are installed to
+	Maintainer   string            `toml:"Maintainer,omitempty"`   // Such as "Jane Doe <jane@example.com>"
+	Name         string            `toml:"Name,omitempty"`         // Defaults to our workspace Name
+	PostInstall  string            `toml:"PostInstall,omitempty"`  // Path to the script run after installing
+	PostRemove   string            `toml:"PostRemove,omitempty"`   // Path to the script run after removing
+	PreInstall   string            `toml:"PreInstall,omitempty"`   // Path to the script run before installing
+	PreRemove    string            `toml:"PreRemove,omitempty"`    // Path to the script run before removing
+	RPMDepends   []string          `toml:"RPMDepends,omitempty"`   // Dependencies only our .rpm has, using Fedora package names
+	Release      string            `toml:"Release,omitempty"`      // Release of this Version of our packages, defaulting to 1
+}
+
+var SupportedChangelogGroups []string     // SupportedChangelogGroups are the ways we group the commits of a release
+var SupportedDistributionFormats []string // SupportedDistributionFormats are our SupportedTarCompressors, along with zip
+var SupportedImageLayouts []string        // SupportedImageLayouts are the ways we write our OCI images
+var SupportedImagePlatforms []string      // SupportedImagePlatforms are the GOOS/GOARCH pairs Go can build for, and thus we can create images of
+var SupportedPackageFormats []string      // SupportedPackageFormats are the packages we can create
+var SupportedPublishTypes []string        // SupportedPublishTypes are the targets we can publish to
+var SupportedTarCompressors []string      // SupportedTarCompressions are various compressions we officially support
+var noodles NoodlesConfig                 // Our Noodles Config
 
 func init() {
+	SupportedChangelogGroups = []string{"project", "type"}
 	SupportedTarCompressors = []string{"bzip2", "gzip", "lzma", "xz", "zstd"}
+	SupportedDistributionFormats = append(append([]string{}, SupportedTarCompressors...), "zip")
+	SupportedImageLayouts = []string{"dir", "tar"}
+	SupportedImagePlatforms = []string{ // Same as go tool dist list
+		"aix/ppc64",
+		"android/386", "android/amd64", "android/arm", "android/arm64",
+		"darwin/amd64", "darwin/arm64",
+		"dragonfly/amd64",
+		"freebsd/386", "freebsd/amd64", "freebsd/arm", "freebsd/arm64",
+		"illumos/amd64",
+		"ios/amd64", "ios/arm64",
+		"js/wasm",
+		"linux/386", "linux/amd64", "linux/arm", "linux/arm64", "linux/loong64", "linux/mips", "linux/mips64", "linux/mips64le", "linux/mipsle", "linux/ppc64", "linux/ppc64le", "linux/riscv64", "linux/s390x",
+		"netbsd/386", "netbsd/amd64", "netbsd/arm", "netbsd/arm64",
+		"openbsd/386", "openbsd/amd64", "openbsd/arm", "openbsd/arm64", "openbsd/ppc64", "openbsd/riscv64",
+		"plan9/386", "plan9/amd64", "plan9/arm",
+		"solaris/amd64",
+		"wasip1/wasm",
+		"windows/386", "windows/amd64", "windows/arm64",
+	}
+	SupportedPackageFormats = []string{"deb", "rpm"}
+	SupportedPublishTypes = []string{"dir", "http", "s3"}
 }
 
 // ReadConfig will read any local noodles.toml that exists and returns an error or NoodlesConfig
@@ -50,19 +139,46 @@ func ReadConfig(configPath string) (conf NoodlesConfig, readConfigErr error) {
 		}
 
 		for _, compressor := range compressors {
-			if !ListContains(SupportedTarCompressors, compressor) { // If the provided compressor isn't supported
-				readConfigErr = errors.New("Must use a supported compressor: " + strings.Join(SupportedTarCompressors, ","))
+			if !ListContains(SupportedDistributionFormats, compressor) { // If the provided compressor isn't supported
+				readConfigErr = errors.New("Must use a supported compressor or zip: " + strings.Join(SupportedDistributionFormats, ","))
+				return
+			}
+		}
+
+		for _, format := range conf.Distribution.PackageFormats {
+			if !ListContainsExact(SupportedPackageFormats, format) { // If the provided package format isn't supported
+				readConfigErr = errors.New("Must use a supported package format: " + strings.Join(SupportedPackageFormats, ","))
 				return
 			}
 		}
 
+		if group := conf.Distribution.ChangelogGroup; group != "" && !ListContainsExact(SupportedChangelogGroups, group) {
+			readConfigErr = errors.New("Distribution ChangelogGroup must be one of: " + strings.Join(SupportedChangelogGroups, ","))
+			return
+		}
+
+		if name := conf.Distribution.ProjectArchiveName; name != "" && !strings.Contains(name, "{project}") { // Every project would have the same archive
+			readConfigErr = errors.New("Distribution ProjectArchiveName must contain {project}")
+			return
+		}
+
 		for name, project := range conf.Projects { // For each noodles project
 			if project.ConsolidateChildDirs && (project.SimpleName == "") { // No SimpleName defined, and it'll be required during consolidation
 				project.SimpleName = name
 			}
 
+			project.Name = name
 			project.SourceDir = filepath.Dir(project.Source)
 
+			if project.ModuleDir != "" { // If a module directory is declared
+				project.ModuleDir = filepath.Clean(project.ModuleDir)
+
+				if filepath.IsAbs(project.ModuleDir) || strings.HasPrefix(project.ModuleDir, "..") { // Must be within our workspace
+					readConfigErr = errors.New(name + ": ModuleDir must be a relative path within the workspace")
+					return
+				}
+			}
+
 			if project.SourceDir != "" { // If SourceDir has content
 				project.SourceDir = project.SourceDir + "/" // Add trailing /
 			}
@@ -83,6 +199,53 @@ func ReadConfig(configPath string) (conf NoodlesConfig, readConfigErr error) {
 
 			conf.Projects[name] = project
 		}
+
+		for name, target := range conf.Distribution.Publish { // For each publish target
+			if !ListContainsExact(SupportedPublishTypes, target.Type) {
+				readConfigErr = errors.New(name + ": publish Type must be one of: " + strings.Join(SupportedPublishTypes, ","))
+				return
+			}
+
+			if (target.Type == "dir" && target.Path == "") || (target.Type == "http" && target.URL == "") || (target.Type == "s3" && target.Bucket == "") { // Nowhere to publish to
+				readConfigErr = errors.New(name + ": publish targets of type dir need a Path, http a URL and s3 a Bucket")
+				return
+			}
+		}
+
+		for name, script := range conf.Scripts { // For each script
+			for key := range script.Env {
+				if key == "" || strings.Contains(key, "=") { // Not a name we can set
+					readConfigErr = errors.New(name + ": " + key + " is not a valid script Env name")
+					return
+				}
+			}
+
+			for param := range script.Params {
+				if !scriptParamRegex.MatchString(param) || param == "args" { // Not a name we can use in a placeholder
+					readConfigErr = errors.New(name + ": " + param + " is not a valid script param, as names are made of letters, numbers, - and _, and args is reserved for extra arguments")
+					return
+				}
+			}
+		}
+
+		for name, image := range conf.Distribution.Images { // For each OCI image
+			if project, exists := conf.Projects[name]; !exists || project.Plugin != "go" || (project.Type != "" && project.Type != "binary") { // Only Go binaries can be run in an image
+				readConfigErr = errors.New("Distribution Images can only be created of Go binary projects, which " + name + " is not")
+				return
+			}
+
+			if image.Layout != "" && !ListContainsExact(SupportedImageLayouts, image.Layout) {
+				readConfigErr = errors.New(name + ": image Layout must be one of: " + strings.Join(SupportedImageLayouts, ","))
+				return
+			}
+
+			for _, platform := range image.Platforms {
+				if !ListContainsExact(SupportedImagePlatforms, platform) { // Not something Go builds for
+					readConfigErr = errors.New(name + ": " + platform + " is not a valid image platform, such as linux/amd64")
+					return
+				}
+			}
+		}
 	} else { // If there was an error decoding'
 		if strings.Contains(convErr.Error(), "no such file or directory") {
 			readConfigErr = errors.New("noodles.toml does not exist in this directory")
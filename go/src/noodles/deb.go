@@ -0,0 +1,313 @@
+package main
+
+// This file contains functionality pertaining to creating .deb packages in-process
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// debScripts are the names of the maintainer scripts in our control archive, by when they run
+var debScripts = map[string]string{
+	"PostInstall": "postinst",
+	"PostRemove":  "postrm",
+	"PreInstall":  "preinst",
+	"PreRemove":   "prerm",
+}
+
+// debOperators are the Debian forms of our dependency operators which differ from them
+var debOperators = map[string]string{
+	"<": "<<",
+	">": ">>",
+}
+
+// WriteDeb will write a .deb package, being an ar archive of its format version, control archive and data archive
+func WriteDeb(w io.Writer, info PackageInfo, epoch time.Time) (writeErr error) {
+	var data, control bytes.Buffer
+	var md5sums string
+
+	if md5sums, writeErr = WriteDebData(&data, info, epoch); writeErr != nil {
+		writeErr = fmt.Errorf("Failed to create data.tar.gz:\n%s", writeErr.Error())
+		return
+	}
+
+	if writeErr = WriteDebControl(&control, info, md5sums, epoch); writeErr != nil {
+		writeErr = fmt.Errorf("Failed to create control.tar.gz:\n%s", writeErr.Error())
+		return
+	}
+
+	if _, writeErr = io.WriteString(w, "!<arch>\n"); writeErr != nil { // ar magic
+		return
+	}
+
+	members := []struct {
+		Content []byte
+		Name    string
+	}{
+		{[]byte("2.0\n"), "debian-binary"}, // Must be first
+		{control.Bytes(), "control.tar.gz"},
+		{data.Bytes(), "data.tar.gz"},
+	}
+
+	for _, member := range members {
+		if writeErr = WriteArMember(w, member.Name, member.Content, epoch); writeErr != nil {
+			break
+		}
+	}
+
+	return
+}
+
+// WriteArMember will write a member of an ar archive, owned by root
+func WriteArMember(w io.Writer, name string, content []byte, epoch time.Time) (writeErr error) {
+	header := fmt.Sprintf("%-16s%-12d%-6d%-6d%-8o%-10d`\n", name, epoch.Unix(), 0, 0, 0100644, len(content))
+
+	if _, writeErr = io.WriteString(w, header); writeErr != nil {
+		return
+	}
+
+	if _, writeErr = w.Write(content); writeErr == nil && len(content)%2 != 0 { // Members are aligned to two bytes
+		_, writeErr = w.Write([]byte{'\n'})
+	}
+
+	return
+}
+
+// WriteDebData will write the gzipped tarball of the files installed by our package, returning their md5sums
+func WriteDebData(w io.Writer, info PackageInfo, epoch time.Time) (md5sums string, writeErr error) {
+	var compressorWriter io.WriteCloser
+	if compressorWriter, writeErr = NewCompressor(w, "gzip"); writeErr != nil {
+		return
+	}
+
+	tarWriter := tar.NewWriter(compressorWriter)
+	files := append([]PackageFile{}, info.Files...)
+	copyright := GetDebCopyright(info)
+	copyrightPath := "/usr/share/doc/" + info.Name + "/copyright"
+
+	for _, file := range files {
+		if file.Path == copyrightPath { // Already ship our own
+			copyright = nil
+		}
+	}
+
+	if copyright != nil { // Ship our License the way Debian does
+		files = append(files, PackageFile{Mode: 0644, ModTime: epoch, Path: copyrightPath, Size: int64(len(copyright))})
+	}
+
+	sort.Slice(files, func(a, b int) bool { return files[a].Path < files[b].Path })
+	dirs := map[string]bool{"/": true}
+	writeErr = WriteDebTarHeader(tarWriter, "./", tar.TypeDir, 0755, 0, epoch)
+
+	for _, file := range files {
+		if writeErr != nil {
+			break
+		}
+
+		var parents []string
+
+		for dir := path.Dir(file.Path); !dirs[dir]; dir = path.Dir(dir) { // Every parent directory we haven't written yet, deepest first
+			parents = append([]string{dir}, parents...)
+			dirs[dir] = true
+		}
+
+		for _, dir := range parents {
+			if writeErr = WriteDebTarHeader(tarWriter, "."+dir+"/", tar.TypeDir, 0755, 0, epoch); writeErr != nil {
+				break
+			}
+		}
+
+		if writeErr != nil {
+			break
+		}
+
+		if writeErr = WriteDebTarHeader(tarWriter, "."+file.Path, tar.TypeReg, int64(file.Mode), file.Size, file.ModTime); writeErr != nil {
+			break
+		}
+
+		hasher := md5.New()
+		contentWriter := io.MultiWriter(tarWriter, hasher)
+
+		if file.Source == "" { // Our copyright
+			_, writeErr = contentWriter.Write(copyright)
+		} else {
+			writeErr = CopyFileTo(contentWriter, file.Source)
+		}
+
+		md5sums += hex.EncodeToString(hasher.Sum(nil)) + "  " + strings.TrimPrefix(file.Path, "/") + "\n"
+	}
+
+	if closeErr := tarWriter.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	if closeErr := compressorWriter.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	return
+}
+
+// WriteDebControl will write the gzipped tarball of our control file, md5sums, conffiles and maintainer scripts
+func WriteDebControl(w io.Writer, info PackageInfo, md5sums string, epoch time.Time) (writeErr error) {
+	var compressorWriter io.WriteCloser
+	if compressorWriter, writeErr = NewCompressor(w, "gzip"); writeErr != nil {
+		return
+	}
+
+	tarWriter := tar.NewWriter(compressorWriter)
+	var conffiles string
+
+	for _, file := range info.Files {
+		if file.Config { // Kept across upgrades
+			conffiles += file.Path + "\n"
+		}
+	}
+
+	members := map[string]string{
+		"control": GetDebControl(info),
+		"md5sums": md5sums,
+	}
+
+	if conffiles != "" {
+		members["conffiles"] = conffiles
+	}
+
+	scripts := make(map[string]bool)
+
+	for name, script := range info.Scripts {
+		members[debScripts[name]] = script
+		scripts[debScripts[name]] = true
+	}
+
+	names := []string{}
+
+	for name := range members {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	writeErr = WriteDebTarHeader(tarWriter, "./", tar.TypeDir, 0755, 0, epoch)
+
+	for _, name := range names {
+		if writeErr != nil {
+			break
+		}
+
+		var mode int64 = 0644
+
+		if scripts[name] { // Must be executable
+			mode = 0755
+		}
+
+		if writeErr = WriteDebTarHeader(tarWriter, "./"+name, tar.TypeReg, mode, int64(len(members[name])), epoch); writeErr == nil {
+			_, writeErr = io.WriteString(tarWriter, members[name])
+		}
+	}
+
+	if closeErr := tarWriter.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	if closeErr := compressorWriter.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	return
+}
+
+// WriteDebTarHeader will write the header of a file or directory owned by root, the way dpkg-deb does
+func WriteDebTarHeader(tarWriter *tar.Writer, name string, typeflag byte, mode, size int64, modTime time.Time) error {
+	return tarWriter.WriteHeader(&tar.Header{
+		Format:   tar.FormatGNU,
+		Gname:    "root",
+		Mode:     mode,
+		ModTime:  modTime,
+		Name:     name,
+		Size:     size,
+		Typeflag: typeflag,
+		Uname:    "root",
+	})
+}
+
+// GetDebControl will return the control file of our package
+func GetDebControl(info PackageInfo) string {
+	var installedSize int64
+
+	for _, file := range info.Files {
+		installedSize += (file.Size + 1023) / 1024 // In KiB, rounded up per file the way dpkg-gencontrol does
+	}
+
+	control := "Package: " + info.Name + "\n"
+	control += "Version: " + info.Version + "-" + info.Release + "\n"
+	control += "Architecture: " + info.Arch + "\n"
+	control += "Maintainer: " + info.Maintainer + "\n"
+	control += fmt.Sprintf("Installed-Size: %d\n", installedSize)
+
+	if len(info.Depends) != 0 {
+		depends := []string{}
+
+		for _, dependency := range info.Depends {
+			if dependency.Operator == "" { // Any version
+				depends = append(depends, dependency.Name)
+				continue
+			}
+
+			operator := dependency.Operator
+
+			if debOperator, exists := debOperators[operator]; exists {
+				operator = debOperator
+			}
+
+			depends = append(depends, dependency.Name+" ("+operator+" "+dependency.Version+")")
+		}
+
+		control += "Depends: " + strings.Join(depends, ", ") + "\n"
+	}
+
+	control += "Section: misc\n"
+	control += "Priority: optional\n"
+
+	if info.Homepage != "" {
+		control += "Homepage: " + info.Homepage + "\n"
+	}
+
+	lines := strings.Split(strings.TrimSpace(info.Description), "\n")
+	control += "Description: " + strings.TrimSpace(lines[0]) + "\n" // Synopsis
+
+	for _, line := range lines[1:] { // Extended description, where each line is indented and blank lines are a .
+		if line = strings.TrimRight(line, " \t\r"); line == "" {
+			control += " .\n"
+		} else {
+			control += " " + line + "\n"
+		}
+	}
+
+	return control
+}
+
+// GetDebCopyright will return the machine-readable copyright file of our package, if we have a License
+func GetDebCopyright(info PackageInfo) []byte {
+	if info.License == "" {
+		return nil
+	}
+
+	copyright := "Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\n"
+	copyright += "Upstream-Name: " + info.Name + "\n"
+
+	if info.Homepage != "" {
+		copyright += "Source: " + info.Homepage + "\n"
+	}
+
+	copyright += "\nFiles: *\nLicense: " + info.License + "\n"
+
+	return []byte(copyright)
+}
@@ -0,0 +1,189 @@
+package main
+
+// This file contains our structured diagnostics, parsed from compiler and linter output and rendered consistently
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is a single problem reported by a compiler or linter
+type Diagnostic struct {
+	Code     string
+	Column   int
+	File     string
+	Line     int
+	Message  string
+	Severity string
+}
+
+// DiagnosticsError is an error consisting of diagnostics, falling back to the raw output if none could be parsed
+type DiagnosticsError struct {
+	Diagnostics []Diagnostic
+	Output      string
+}
+
+// DiagnosticsFormatPretty renders each diagnostic as its location and code, followed by its message on the next line
+const DiagnosticsFormatPretty = "pretty"
+
+// DiagnosticsFormatProblemMatcher renders each diagnostic on a single file:line:col: severity code: message line, for editors
+const DiagnosticsFormatProblemMatcher = "problem-matcher"
+
+var diagnosticsFormat string // The format we render diagnostics in
+
+var goDiagnosticRegex = regexp.MustCompile(`^(\S+\.go):(\d+)(?::(\d+))?: (.+)$`)
+var lessDiagnosticRegex = regexp.MustCompile(`^(\w+Error): (.+?) in (\S+) on line (\d+), column (\d+):?`)
+var typescriptDiagnosticRegex = regexp.MustCompile(`^(.+?)(?:\((\d+),(\d+)\): |:(\d+):(\d+) - )(error|warning|message) (TS\d+): (.+)$`)
+
+// Error will render the diagnostics, or the raw output if there are none
+func (e *DiagnosticsError) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return strings.TrimSpace(e.Output)
+	}
+
+	return RenderDiagnostics(e.Diagnostics)
+}
+
+// NewDiagnosticsError will return a DiagnosticsError for the provided diagnostics and output
+func NewDiagnosticsError(diagnostics []Diagnostic, output string) error {
+	return &DiagnosticsError{
+		Diagnostics: diagnostics,
+		Output:      output,
+	}
+}
+
+// String will render the diagnostic in our current diagnostics format
+func (d Diagnostic) String() string {
+	location := d.File
+
+	if d.Line != 0 { // Have a line
+		location += ":" + strconv.Itoa(d.Line)
+
+		if d.Column != 0 { // Have a column
+			location += ":" + strconv.Itoa(d.Column)
+		}
+	}
+
+	header := d.Severity
+
+	if d.Code != "" { // Have a code, such as TS2322 or a lint rule
+		header += " " + d.Code
+	}
+
+	if diagnosticsFormat == DiagnosticsFormatProblemMatcher { // Single line
+		return fmt.Sprintf("%s: %s: %s", location, header, strings.Replace(d.Message, "\n", " ", -1))
+	}
+
+	// Example: src/typescript/example/example.ts:2:7: error TS2322
+	// Type 'string' is not assignable to type 'number'.
+	return fmt.Sprintf("%s: %s\n%s", location, header, d.Message)
+}
+
+// RenderDiagnostics will render all of the provided diagnostics, one after another
+func RenderDiagnostics(diagnostics []Diagnostic) string {
+	rendered := []string{}
+
+	for _, diagnostic := range diagnostics {
+		rendered = append(rendered, diagnostic.String())
+	}
+
+	return strings.Join(rendered, "\n")
+}
+
+// PrintDiagnostics will print all of the provided diagnostics
+func PrintDiagnostics(diagnostics []Diagnostic) {
+	if len(diagnostics) != 0 {
+		fmt.Println(RenderDiagnostics(diagnostics))
+	}
+}
+
+// GetDiagnosticPath will return the path relative to our workdir, given the directory the path is relative to
+func GetDiagnosticPath(path, baseDir string) string {
+	if !filepath.IsAbs(path) { // Relative to the tool's working directory
+		path = filepath.Join(baseDir, path)
+	}
+
+	if relPath, relErr := filepath.Rel(workdir, path); relErr == nil && !strings.HasPrefix(relPath, "..") { // Within our workspace
+		path = relPath
+	}
+
+	return path
+}
+
+// MapFlattenedPath will map a path flattened by ConsolidateChildDirs, such as nested__file.go, back to its real path
+func MapFlattenedPath(path string) string {
+	return filepath.Join(filepath.Dir(path), strings.Replace(filepath.Base(path), "__", "/", -1))
+}
+
+// ParseGoDiagnostics will parse the output of go build or go vet, such as file.go:12:5: undefined: x
+func ParseGoDiagnostics(output, baseDir string) (diagnostics []Diagnostic) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		if parts := goDiagnosticRegex.FindStringSubmatch(line); parts != nil {
+			lineNum, _ := strconv.Atoi(parts[2])
+			column, _ := strconv.Atoi(parts[3])
+
+			diagnostics = append(diagnostics, Diagnostic{
+				Column:   column,
+				File:     GetDiagnosticPath(MapFlattenedPath(parts[1]), baseDir),
+				Line:     lineNum,
+				Message:  CleanupGoCompilerOutput(parts[4]),
+				Severity: "error",
+			})
+		} else if len(diagnostics) != 0 && (strings.HasPrefix(line, "have ") || strings.HasPrefix(line, "want ")) { // Continuation of the previous diagnostic
+			diagnostics[len(diagnostics)-1].Message += "\n" + line
+		}
+	}
+
+	return
+}
+
+// ParseLessDiagnostics will parse the output of lessc, such as SyntaxError: Unrecognised input in file.less on line 3, column 5:
+func ParseLessDiagnostics(output, baseDir string) (diagnostics []Diagnostic) {
+	for _, line := range strings.Split(output, "\n") {
+		if parts := lessDiagnosticRegex.FindStringSubmatch(strings.TrimSpace(line)); parts != nil {
+			lineNum, _ := strconv.Atoi(parts[4])
+			column, _ := strconv.Atoi(parts[5])
+
+			diagnostics = append(diagnostics, Diagnostic{
+				Code:     parts[1],
+				Column:   column + 1, // lessc columns are zero-indexed
+				File:     GetDiagnosticPath(parts[3], baseDir),
+				Line:     lineNum,
+				Message:  parts[2],
+				Severity: "error",
+			})
+		}
+	}
+
+	return
+}
+
+// ParseTypeScriptDiagnostics will parse the output of tsc, in either its plain file.ts(12,5): or pretty file.ts:12:5 - format
+func ParseTypeScriptDiagnostics(output, baseDir string) (diagnostics []Diagnostic) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if parts := typescriptDiagnosticRegex.FindStringSubmatch(line); parts != nil {
+			lineNum, _ := strconv.Atoi(parts[2] + parts[4]) // Only one of the formats will have matched
+			column, _ := strconv.Atoi(parts[3] + parts[5])
+
+			diagnostics = append(diagnostics, Diagnostic{
+				Code:     parts[7],
+				Column:   column,
+				File:     GetDiagnosticPath(parts[1], baseDir),
+				Line:     lineNum,
+				Message:  parts[8],
+				Severity: parts[6],
+			})
+		} else if len(diagnostics) != 0 && strings.HasPrefix(line, "  ") && strings.Trim(line, " ~") != "" { // Elaboration of the previous diagnostic, not an underline
+			diagnostics[len(diagnostics)-1].Message += "\n" + strings.TrimSpace(line)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,222 @@
+package main
+
+// This file contains functionality pertaining to the workspace-level Include and Exclude of our archives
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// PackFile is a file we pack into our archives
+type PackFile struct {
+	Path   string // Slash separated path within our archives
+	Source string // Path to the file we copy, relative to our workdir
+}
+
+var DefaultDistributionExcludes []string // DefaultDistributionExcludes are editor temp files and system files we never pack
+
+func init() {
+	DefaultDistributionExcludes = []string{"*~", "*.swp", "*.swo", "#*#", ".#*", ".DS_Store", "Thumbs.db"}
+}
+
+// GetGlobBase will return the directory of the pattern before its first wildcard, which matched files are relative to
+func GetGlobBase(pattern string) string {
+	if !strings.ContainsAny(pattern, "*?") { // Not a glob
+		return filepath.Dir(pattern)
+	}
+
+	return filepath.Dir(pattern[:strings.IndexAny(pattern, "*?")] + "x") // Directory before the wildcard, even if the pattern starts with it
+}
+
+// GetDistributionIncludes will return the files matching each of our Distribution Include patterns, at the archive directory they map to
+func GetDistributionIncludes() (files []PackFile, includeErr error) {
+	patterns := []string{}
+
+	for pattern := range noodles.Distribution.Include {
+		patterns = append(patterns, pattern)
+	}
+
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns { // For each pattern, in the same order every time
+		archiveDir := filepath.ToSlash(noodles.Distribution.Include[pattern])
+		base := GetGlobBase(pattern)
+		matches := []string{}
+
+		if strings.ContainsAny(pattern, "*?") { // Glob
+			matches = GlobFiles(filepath.Join(workdir, pattern))
+		} else if info, statErr := os.Stat(filepath.Join(workdir, pattern)); statErr == nil && info.IsDir() { // Directory, so its contents are relative to it
+			base = pattern
+			matches = GlobFiles(filepath.Join(workdir, pattern, "**"))
+		} else if statErr == nil { // File
+			matches = append(matches, filepath.Join(workdir, pattern))
+		}
+
+		if len(matches) == 0 {
+			includeErr = errors.New("Distribution Include " + pattern + " does not match any files")
+			return
+		}
+
+		for _, match := range matches {
+			source, _ := filepath.Rel(workdir, match)
+			relPath, _ := filepath.Rel(filepath.Join(workdir, base), match)
+
+			files = append(files, PackFile{
+				Path:   path.Join(archiveDir, filepath.ToSlash(relPath)),
+				Source: source,
+			})
+		}
+	}
+
+	return
+}
+
+// IsDistributionExcluded will return whether the file matches one of our Distribution Exclude patterns or default excludes
+// Patterns without a slash match the name of the file at any depth, otherwise they match its source or archive path
+func IsDistributionExcluded(file PackFile) bool {
+	for _, pattern := range append(append([]string{}, DefaultDistributionExcludes...), noodles.Distribution.Exclude...) {
+		if !strings.Contains(pattern, "/") { // Name of the file
+			if GlobRegexp(pattern).MatchString(path.Base(file.Path)) {
+				return true
+			}
+		} else if matcher := GlobRegexp(strings.TrimPrefix(pattern, "/")); matcher.MatchString(file.Path) || matcher.MatchString(filepath.ToSlash(file.Source)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PackArchive is a set of files we create archives of, in each of our distribution formats
+type PackArchive struct {
+	Name     string                    // Name of the archives, without their extension
+	Projects map[string]NoodlesProject // Projects whose files we pack, along with our Distribution Include
+}
+
+const DefaultProjectArchiveName = "{project}-{version}{platform}"
+
+// CondenseArchiveName will lowercase the name and replace its whitespace with _, so it is suitable for an archive name
+func CondenseArchiveName(name string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(name)), " ", "_", -1)
+}
+
+// GetOutputDir will return the directory our archives are written to
+func GetOutputDir() string {
+	if noodles.Distribution.OutputDir == "" { // No OutputDir set, so use our workspace
+		return "."
+	}
+
+	return filepath.Clean(noodles.Distribution.OutputDir)
+}
+
+// GetArchivePath will return the path to the archive with the provided name in the provided format, such as noodles-0.1.0.tar.zst
+func GetArchivePath(name, format string) string {
+	return filepath.Join(GetOutputDir(), name+GetArchiveExtension(format))
+}
+
+// GetProjectArchiveName will return the name of the project's archive from our ProjectArchiveName
+// {platform} is the -os-arch of Go binaries and plugins, which only run on the platform they were built for
+func GetProjectArchiveName(project NoodlesProject) string {
+	name := noodles.Distribution.ProjectArchiveName
+
+	if name == "" {
+		name = DefaultProjectArchiveName
+	}
+
+	goos, goarch := GetGoPlatform()
+	platform := ""
+
+	if project.Plugin == "go" && project.Type != "package" { // Platform specific
+		platform = "-" + goos + "-" + goarch
+	}
+
+	replacer := strings.NewReplacer(
+		"{arch}", goarch,
+		"{os}", goos,
+		"{platform}", platform,
+		"{project}", CondenseArchiveName(project.Name),
+		"{version}", noodles.Version.String(),
+		"{workspace}", CondenseArchiveName(noodles.Name),
+	)
+
+	return replacer.Replace(name)
+}
+
+// GetGoPlatform will return the operating system and architecture go builds for, which GOOS and GOARCH override
+func GetGoPlatform() (goos, goarch string) {
+	if goos = os.Getenv("GOOS"); goos == "" {
+		goos = runtime.GOOS
+	}
+
+	if goarch = os.Getenv("GOARCH"); goarch == "" {
+		goarch = runtime.GOARCH
+	}
+
+	return
+}
+
+// GetPackArchives will return our combined archive of the provided projects and, if enabled, an archive for each of them
+func GetPackArchives(projectsToPack map[string]NoodlesProject) []PackArchive {
+	archives := []PackArchive{
+		{
+			Name:     CondenseArchiveName(noodles.Name) + "-" + noodles.Version.String(),
+			Projects: projectsToPack,
+		},
+	}
+
+	if !noodles.Distribution.ProjectArchives && !packPerProject { // Only our combined archive
+		return archives
+	}
+
+	names := []string{}
+
+	for name, project := range projectsToPack {
+		if project.Plugin != "" { // Has files to pack
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		archives = append(archives, PackArchive{
+			Name:     GetProjectArchiveName(projectsToPack[name]),
+			Projects: map[string]NoodlesProject{name: projectsToPack[name]},
+		})
+	}
+
+	return archives
+}
+
+// GetDistributionArtifacts will return the path to every archive, package and image tarball pack creates of the provided projects
+func GetDistributionArtifacts(projectsToPack map[string]NoodlesProject) (artifacts []string, artifactsErr error) {
+	artifacts = []string{}
+
+	for _, archive := range GetPackArchives(projectsToPack) { // For our combined archive and any project archives
+		for _, compressor := range noodles.Distribution.TarCompressors {
+			artifacts = append(artifacts, GetArchivePath(archive.Name, compressor))
+		}
+	}
+
+	for _, format := range noodles.Distribution.PackageFormats {
+		var packagePath string
+		if packagePath, artifactsErr = GetPackagePath(projectsToPack, format); artifactsErr != nil {
+			return
+		}
+
+		artifacts = append(artifacts, packagePath)
+	}
+
+	for _, name := range GetImageNames(projectsToPack) {
+		if noodles.Distribution.Images[name].Layout != "dir" { // Image directories aren't checksummed or signed
+			artifacts = append(artifacts, GetImagePath(name))
+		}
+	}
+
+	return
+}
@@ -0,0 +1,162 @@
+package main
+
+// This file contains functionality pertaining to linting TypeScript projects with ESLint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ESLintFileResult is the result of linting a single file, as output by eslint --format json
+type ESLintFileResult struct {
+	FilePath string
+	Messages []ESLintMessage
+}
+
+// ESLintMessage is a single problem reported by ESLint
+type ESLintMessage struct {
+	Column   int
+	Fatal    bool
+	Line     int
+	Message  string
+	RuleID   string `json:"ruleId"`
+	Severity int
+}
+
+// ESLintConfigFiles are the config file names ESLint supports, in the order ESLint itself prefers them
+var ESLintConfigFiles []string
+
+// ESLintFlatConfigFiles are the config file names using ESLint's flat config format
+var ESLintFlatConfigFiles []string
+
+func init() {
+	ESLintFlatConfigFiles = []string{"eslint.config.js", "eslint.config.mjs", "eslint.config.cjs"}
+	ESLintConfigFiles = append(ESLintFlatConfigFiles, ".eslintrc.js", ".eslintrc.cjs", ".eslintrc.yaml", ".eslintrc.yml", ".eslintrc.json", ".eslintrc")
+}
+
+// FindESLintConfig will look for an ESLint config from the provided directory up to our workdir, returning an empty string if there is none
+func FindESLintConfig(dir string) string {
+	var configPath string
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workdir, dir)
+	}
+
+	for {
+		for _, configFile := range ESLintConfigFiles { // For each supported config file
+			if _, statErr := os.Stat(filepath.Join(dir, configFile)); statErr == nil { // Config exists
+				configPath = filepath.Join(dir, configFile)
+				break
+			}
+		}
+
+		if configPath != "" || dir == workdir || !strings.HasPrefix(dir, workdir) { // Found a config or reached the root of our workspace
+			break
+		}
+
+		dir = filepath.Dir(dir)
+	}
+
+	return configPath
+}
+
+// GenerateESLintConfig will generate our default ESLint config, using the TypeScript parser and its recommended rules
+// The parser is resolved relative to the config, so when provided the global npm root we'll point the parser there
+func GenerateESLintConfig(npmRoot string) (configPath string, generateErr error) {
+	configPath = filepath.Join(workdir, ".noodles", "eslintrc.json")
+	parser := "@typescript-eslint/parser"
+
+	if npmRoot != "" { // Have the global npm root
+		parser = filepath.Join(npmRoot, parser)
+	}
+
+	config := map[string]interface{}{
+		"root":    true,
+		"parser":  parser,
+		"plugins": []string{"@typescript-eslint"},
+		"extends": []string{"eslint:recommended", "plugin:@typescript-eslint/recommended"},
+	}
+
+	var content []byte
+	if content, generateErr = json.MarshalIndent(config, "", "\t"); generateErr != nil { // Failed to encode our config
+		return
+	}
+
+	if generateErr = os.MkdirAll(filepath.Dir(configPath), 0755); generateErr == nil { // Created our config dir
+		generateErr = ioutil.WriteFile(configPath, append(content, '\n'), 0644)
+	}
+
+	return
+}
+
+// RunESLint will run ESLint against the provided files, discovering the config from the project's source directory
+func RunESLint(n *NoodlesProject, files []string, fix bool) (results []ESLintFileResult, lintErr error) {
+	args := []string{"--format", "json"}
+	env := os.Environ()
+
+	configPath := FindESLintConfig(n.SourceDir)
+
+	if configPath == "" { // No config for this project, use our own
+		npmRoot := GetNpmGlobalRoot() // Where our globally installed packages live
+
+		if configPath, lintErr = GenerateESLintConfig(npmRoot); lintErr != nil {
+			lintErr = errors.New("failed to generate ESLint config: " + lintErr.Error())
+			return
+		}
+
+		args = append(args, "--no-eslintrc")
+
+		if npmRoot != "" { // Resolve plugins from our globally installed packages
+			args = append(args, "--resolve-plugins-relative-to", npmRoot)
+		}
+	}
+
+	if !ListContainsExact(ESLintFlatConfigFiles, filepath.Base(configPath)) { // eslintrc style config
+		env = append(env, "ESLINT_USE_FLAT_CONFIG=false")
+	}
+
+	args = append(args, "--config", configPath)
+
+	if fix { // Fix problems where possible
+		args = append(args, "--fix")
+	}
+
+	args = append(args, files...)
+
+	var stdout, stderr bytes.Buffer
+	linter := exec.Command("eslint", args...)
+	linter.Env = env
+	linter.Stdout = &stdout
+	linter.Stderr = &stderr
+
+	runErr := linter.Run()
+
+	if exitErr, isExitErr := runErr.(*exec.ExitError); runErr != nil && (!isExitErr || exitErr.ExitCode() != 1) { // Exit code 1 only means problems were found
+		lintErr = errors.New("eslint failed: " + strings.TrimSpace(stderr.String()+"\n"+stdout.String()))
+		return
+	}
+
+	if parseErr := json.Unmarshal(stdout.Bytes(), &results); parseErr != nil { // Failed to parse the results
+		lintErr = fmt.Errorf("failed to parse eslint output: %s\n%s", parseErr.Error(), stderr.String())
+	}
+
+	return
+}
+
+// ESLintSeverityConfidence will return the lint confidence equivalent to an ESLint severity, so they can be filtered by --confidence
+func ESLintSeverityConfidence(message ESLintMessage) float64 {
+	confidence := 0.5 // Warnings
+
+	if message.Severity >= 2 || message.Fatal { // Errors
+		confidence = 1
+	}
+
+	return confidence
+}
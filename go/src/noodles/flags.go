@@ -0,0 +1,72 @@
+package main
+
+// This file contains functionality pertaining to the Flags and MinifierFlags passed through to our compilers and minifiers
+
+import (
+	"errors"
+	"strings"
+)
+
+// GoReservedFlags are the go build flags noodles sets itself
+var GoReservedFlags []string
+
+// LessReservedFlags are the lessc flags noodles sets itself
+var LessReservedFlags []string
+
+// TerserReservedFlags are the terser flags noodles sets itself
+var TerserReservedFlags []string
+
+// TypeScriptReservedFlags are the tsc flags noodles sets itself, either on the command line or in our generated tsconfig.json
+var TypeScriptReservedFlags []string
+
+func init() {
+	GoReservedFlags = []string{"-buildmode", "-o"}
+	LessReservedFlags = []string{"--lint", "-l", "--source-map"}
+	TerserReservedFlags = []string{"--output", "-o", "--source-map"}
+	TypeScriptReservedFlags = []string{"--build", "-b", "--composite", "--out", "--outDir", "--outFile", "--project", "-p", "--target", "-t", "--watch", "-w"}
+}
+
+// GetFlagName will return the name of the provided flag without its dashes or value, such as outFile for --outFile=app.js
+func GetFlagName(flag string) string {
+	return strings.TrimLeft((strings.SplitN(flag, "=", 2))[0], "-")
+}
+
+// GetConflictingFlags will return the flags from those provided which conflict with the reserved flags
+func GetConflictingFlags(flags, reserved []string) []string {
+	conflicting := []string{}
+
+	for _, flag := range flags { // For each flag
+		if !strings.HasPrefix(flag, "-") { // Value of a previous flag or an argument
+			continue
+		}
+
+		for _, reservedFlag := range reserved { // For each flag we set ourselves
+			if strings.EqualFold(GetFlagName(flag), GetFlagName(reservedFlag)) { // Same flag, regardless of the dashes used or its casing
+				conflicting = append(conflicting, flag)
+				break
+			}
+		}
+	}
+
+	return conflicting
+}
+
+// ValidateFlags will return an error if any of the flags conflict with the reserved flags, so we never pass them through
+func ValidateFlags(field string, flags, reserved []string) (validateErr error) {
+	if conflicting := GetConflictingFlagsErrors(field, flags, reserved); len(conflicting) != 0 {
+		validateErr = errors.New(strings.Join(conflicting, "\n"))
+	}
+
+	return
+}
+
+// GetConflictingFlagsErrors will return check errors for any of the flags which conflict with the reserved flags
+func GetConflictingFlagsErrors(field string, flags, reserved []string) []string {
+	errors := []string{}
+
+	for _, flag := range GetConflictingFlags(flags, reserved) {
+		errors = append(errors, field+" contains "+flag+", which noodles sets itself. Remove it.")
+	}
+
+	return errors
+}
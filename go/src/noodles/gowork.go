@@ -0,0 +1,160 @@
+package main
+
+// This file contains functionality pertaining to Go workspaces (go.work) spanning multiple Go Modules
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GoWorkFile is the name of the Go workspace file we generate in the root of the noodles workspace
+const GoWorkFile = "go.work"
+
+// GetModuleDir will return the absolute path to the directory containing this project's go.mod
+func (n *NoodlesProject) GetModuleDir() string {
+	return filepath.Join(workdir, n.ModuleDir) // ModuleDir is relative to our workdir, defaulting to the workdir itself
+}
+
+// ExecGoCommand will run go with the provided args in the provided directory, returning an error containing the output on failure
+func ExecGoCommand(dir string, args []string) (execErr error) {
+	runner := exec.Command("go", args...)
+	runner.Dir = dir
+
+	if output, runErr := runner.CombinedOutput(); runErr != nil { // If go failed
+		execErr = errors.New("go " + strings.Join(args, " ") + " failed: " + CleanupGoCompilerOutput(string(output)))
+	}
+
+	return
+}
+
+// GetGoModuleDirs will return the sorted, unique module directories (relative to our workdir) of all Go projects using Go Modules
+func GetGoModuleDirs() []string {
+	dirs := []string{}
+
+	for _, project := range noodles.Projects { // For each project
+		if project.Plugin != "go" || !project.EnableGoModules { // Not a Go project using Go Modules
+			continue
+		}
+
+		dir := filepath.ToSlash(filepath.Clean(project.ModuleDir)) // An empty ModuleDir cleans to .
+
+		if dir != "." { // Not the workdir itself
+			dir = "./" + dir // go.work uses ./ prefixed relative paths
+		}
+
+		if !ListContainsExact(dirs, dir) { // Not already added
+			dirs = append(dirs, dir)
+		}
+	}
+
+	sort.Strings(dirs)
+
+	return dirs
+}
+
+// UsesGoWorkspace will return whether any Go project declares its own module directory, requiring a go.work
+func UsesGoWorkspace() bool {
+	var uses bool
+
+	for _, project := range noodles.Projects { // For each project
+		if project.Plugin == "go" && project.EnableGoModules && project.ModuleDir != "" { // Go project with a declared module dir
+			uses = true
+			break
+		}
+	}
+
+	return uses
+}
+
+// ReadGoWorkUses will return the directories from all use directives in the provided go.work file
+func ReadGoWorkUses(goWorkPath string) (uses []string, readErr error) {
+	var goWork *os.File
+
+	if goWork, readErr = os.Open(goWorkPath); readErr != nil { // Failed to open go.work
+		return
+	}
+
+	defer goWork.Close()
+
+	inUseBlock := false
+	scanner := bufio.NewScanner(goWork)
+
+	for scanner.Scan() { // For each line
+		line := scanner.Text()
+
+		if commentIndex := strings.Index(line, "//"); commentIndex != -1 { // Has a comment
+			line = line[:commentIndex] // Strip the comment
+		}
+
+		line = strings.TrimSpace(line)
+
+		if inUseBlock { // Inside of a use ( ... ) block
+			if line == ")" { // End of block
+				inUseBlock = false
+			} else if line != "" {
+				uses = append(uses, strings.Trim(line, `"`))
+			}
+		} else if strings.HasPrefix(line, "use ") || strings.HasPrefix(line, "use(") { // Use directive
+			directive := strings.TrimSpace(strings.TrimPrefix(line, "use"))
+
+			if directive == "(" { // Start of block
+				inUseBlock = true
+			} else if directive != "" {
+				uses = append(uses, strings.Trim(directive, `"`))
+			}
+		}
+	}
+
+	readErr = scanner.Err()
+
+	return
+}
+
+// GetMissingGoWorkUses will return the module directories of our Go projects which are not used in the go.work
+func GetMissingGoWorkUses() (missing []string, readErr error) {
+	var uses []string
+
+	if uses, readErr = ReadGoWorkUses(filepath.Join(workdir, GoWorkFile)); readErr != nil { // Failed to read go.work
+		return
+	}
+
+	cleanedUses := []string{}
+
+	for _, use := range uses { // For each use
+		cleanedUses = append(cleanedUses, filepath.Clean(use))
+	}
+
+	for _, dir := range GetGoModuleDirs() { // For each module dir
+		if !ListContainsExact(cleanedUses, filepath.Clean(dir)) { // Not in the workspace
+			missing = append(missing, dir)
+		}
+	}
+
+	return
+}
+
+// SyncGoWork will generate a go.work if necessary and ensure it uses all of our module directories
+func SyncGoWork() (syncErr error) {
+	if !UsesGoWorkspace() { // Single module workspace
+		return
+	}
+
+	if _, statErr := os.Stat(filepath.Join(workdir, GoWorkFile)); os.IsNotExist(statErr) { // No go.work yet
+		syncErr = ExecGoCommand(workdir, append([]string{"work", "init"}, GetGoModuleDirs()...))
+		return
+	}
+
+	var missing []string
+	if missing, syncErr = GetMissingGoWorkUses(); syncErr != nil || len(missing) == 0 { // Failed to read or nothing missing
+		return
+	}
+
+	syncErr = ExecGoCommand(workdir, append([]string{"work", "use"}, missing...))
+
+	return
+}
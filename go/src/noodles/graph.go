@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"github.com/JoshStrobl/trunk"
+	"github.com/spf13/cobra"
+	"sort"
+	"strings"
+)
+
+var graphCmd = &cobra.Command{
+	Use:               "graph",
+	Short:             "Shows the import graph of LESS projects",
+	Long:              "Shows the files each LESS project imports, which are what determine when it is rebuilt",
+	Run:               graph,
+	DisableAutoGenTag: true,
+}
+
+var graphDot bool
+var graphProject string
+
+func init() {
+	graphCmd.Flags().BoolVarP(&graphDot, "dot", "d", false, "Output the graph in the Graphviz DOT format")
+	graphCmd.Flags().StringVarP(&graphProject, "project", "p", "", "Name of the project we're showing the graph of")
+}
+
+func graph(cmd *cobra.Command, args []string) {
+	names := []string{}
+
+	if graphProject == "" { // If no project is set
+		for name, project := range noodles.Projects { // For each project
+			if project.Plugin == "less" { // Only LESS projects have an import graph
+				names = append(names, name)
+			}
+		}
+	} else if project, exists := noodles.Projects[graphProject]; !exists { // If the project doesn't exist
+		trunk.LogFatal(graphProject + " is not a valid project")
+	} else if project.Plugin != "less" { // If the project doesn't have an import graph
+		trunk.LogFatal(graphProject + " is not a LESS project")
+	} else {
+		names = append(names, graphProject)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		GraphProject(name)
+	}
+}
+
+// GraphProject will print the import graph of the provided LESS project
+func GraphProject(name string) {
+	project := noodles.Projects[name]
+	importGraph := project.GetLessImportGraph()
+
+	if graphDot { // Graphviz
+		fmt.Printf("digraph %q {\n", name)
+
+		for _, file := range importGraph.Files() {
+			for _, imported := range importGraph.Imports[file] {
+				fmt.Printf("\t%q -> %q;\n", GetDiagnosticPath(file, workdir), GetDiagnosticPath(imported, workdir))
+			}
+		}
+
+		fmt.Println("}")
+		return
+	}
+
+	trunk.LogInfo(name)
+
+	if len(importGraph.Sources) == 0 { // Nothing to show
+		trunk.LogWarn("Source " + project.GetDefaultLessSource() + " does not match any files")
+		return
+	}
+
+	printed := make(map[string]bool)
+
+	for _, source := range importGraph.Sources { // For each entry point
+		PrintLessImportTree(importGraph, source, 0, printed, []string{})
+	}
+
+	fmt.Println()
+}
+
+// PrintLessImportTree will print the file and, the first time it is printed, the files it imports, indented by depth
+func PrintLessImportTree(importGraph LessImportGraph, file string, depth int, printed map[string]bool, ancestors []string) {
+	line := strings.Repeat("  ", depth) + GetDiagnosticPath(file, workdir)
+
+	if ListContainsExact(ancestors, file) { // Imports itself through its imports
+		fmt.Println(line + " (circular)")
+		return
+	}
+
+	if printed[file] && len(importGraph.Imports[file]) != 0 { // Already shown what it imports
+		fmt.Println(line + " (see above)")
+		return
+	}
+
+	fmt.Println(line)
+	printed[file] = true
+
+	for _, imported := range importGraph.Imports[file] {
+		PrintLessImportTree(importGraph, imported, depth+1, printed, append(ancestors, file))
+	}
+}
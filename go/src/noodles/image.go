@@ -0,0 +1,563 @@
+package main
+
+// This file contains functionality pertaining to creating OCI image layouts of Go binary projects, without needing a container runtime
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/JoshStrobl/trunk"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	OCIImageConfigMediaType   = "application/vnd.oci.image.config.v1+json"
+	OCIImageIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	OCIImageLayerMediaType    = "application/vnd.oci.image.layer.v1.tar+gzip"
+	OCIImageManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	OCIRefNameAnnotation      = "org.opencontainers.image.ref.name"
+)
+
+const DefaultImagePath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin" // Same PATH as Docker's defaults
+
+// ImagePlatform is a build of a Go binary project for a platform we create an image of
+type ImagePlatform struct {
+	Arch   string
+	Binary string // Path to the build, relative to our workdir
+	OS     string
+}
+
+// OCIDescriptor is a reference to a blob of an OCI image layout
+type OCIDescriptor struct {
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Digest      string            `json:"digest"`
+	MediaType   string            `json:"mediaType"`
+	Platform    *OCIPlatform      `json:"platform,omitempty"`
+	Size        int64             `json:"size"`
+}
+
+// OCIPlatform is the platform an image manifest runs on
+type OCIPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+// OCIIndex is an OCI image index, being index.json or a multi-arch index
+type OCIIndex struct {
+	Manifests     []OCIDescriptor `json:"manifests"`
+	MediaType     string          `json:"mediaType"`
+	SchemaVersion int             `json:"schemaVersion"`
+}
+
+// OCIManifest is an OCI image manifest of a single platform
+type OCIManifest struct {
+	Config        OCIDescriptor   `json:"config"`
+	Layers        []OCIDescriptor `json:"layers"`
+	MediaType     string          `json:"mediaType"`
+	SchemaVersion int             `json:"schemaVersion"`
+}
+
+// OCIImageConfig is the configuration of an OCI image of a single platform
+type OCIImageConfig struct {
+	Architecture string             `json:"architecture"`
+	Config       OCIContainerConfig `json:"config"`
+	Created      string             `json:"created"`
+	OS           string             `json:"os"`
+	RootFS       OCIRootFS          `json:"rootfs"`
+}
+
+// OCIContainerConfig is how containers of an OCI image are run
+type OCIContainerConfig struct {
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	User         string              `json:"User,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+}
+
+// OCIRootFS is the uncompressed digests of the layers of an OCI image
+type OCIRootFS struct {
+	DiffIDs []string `json:"diff_ids"`
+	Type    string   `json:"type"`
+}
+
+// GetImagePath will return the path to the OCI image of the provided project, being a directory or tarball depending on its Layout
+func GetImagePath(name string) string {
+	imagePath := filepath.Join(GetOutputDir(), CondenseArchiveName(name)+"-"+noodles.Version.String()+".oci")
+
+	if noodles.Distribution.Images[name].Layout != "dir" { // Tarball by default
+		imagePath += ".tar"
+	}
+
+	return imagePath
+}
+
+// GetImageNames will return the sorted names of the provided projects which we create an image of
+func GetImageNames(projectsToPack map[string]NoodlesProject) []string {
+	names := []string{}
+
+	for name := range noodles.Distribution.Images {
+		if _, exists := projectsToPack[name]; exists {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// GetImagePlatforms will return the builds of the project we create an image of, being its Destination and any cross-compiled <Destination>-<os>-<arch>
+func GetImagePlatforms(project NoodlesProject, config NoodlesImageConfig) (platforms []ImagePlatform, platformsErr error) {
+	builds := make(map[string]string)       // Path to the build, by os/arch
+	destination := project.GetDestination() // Including the build/name we default to
+
+	if _, statErr := os.Stat(destination); statErr == nil { // Built for our GOOS and GOARCH
+		goos, goarch := GetGoPlatform()
+		builds[goos+"/"+goarch] = destination
+	}
+
+	crossBuilds, _ := filepath.Glob(destination + "-*-*")
+
+	for _, build := range crossBuilds {
+		if parts := strings.Split(strings.TrimPrefix(build, destination+"-"), "-"); len(parts) == 2 && ListContainsExact(SupportedImagePlatforms, parts[0]+"/"+parts[1]) { // Known os and arch, rather than some other file
+			builds[parts[0]+"/"+parts[1]] = build
+		}
+	}
+
+	wanted := config.Platforms
+
+	if len(wanted) == 0 { // Every build we found
+		for platform := range builds {
+			wanted = append(wanted, platform)
+		}
+
+		sort.Strings(wanted)
+	}
+
+	if len(wanted) == 0 {
+		platformsErr = fmt.Errorf("%s has not been built. %s does not exist", project.Name, destination)
+		return
+	}
+
+	for _, platform := range wanted {
+		build, exists := builds[platform]
+		parts := strings.Split(platform, "/")
+
+		if len(parts) != 2 { // Not os/arch
+			platformsErr = errors.New(platform + " is not a valid platform, such as linux/amd64")
+			return
+		} else if !exists {
+			platformsErr = fmt.Errorf("%s has not been built for %s. Build it with GOOS=%s GOARCH=%s to %s-%s-%s", project.Name, platform, parts[0], parts[1], destination, parts[0], parts[1])
+			return
+		}
+
+		platforms = append(platforms, ImagePlatform{Arch: parts[1], Binary: build, OS: parts[0]})
+	}
+
+	return
+}
+
+// GetImageLabels will return the labels of our image, built from our workspace and its Labels
+func GetImageLabels(config NoodlesImageConfig, epoch time.Time) map[string]string {
+	labels := map[string]string{
+		"org.opencontainers.image.created": epoch.Format(time.RFC3339),
+		"org.opencontainers.image.title":   noodles.Name,
+		"org.opencontainers.image.version": noodles.Version.String(),
+	}
+
+	if noodles.Description != "" {
+		labels["org.opencontainers.image.description"] = noodles.Description
+	}
+
+	if noodles.License != "" {
+		labels["org.opencontainers.image.licenses"] = noodles.License
+	}
+
+	if noodles.Distribution.Package.Homepage != "" {
+		labels["org.opencontainers.image.url"] = noodles.Distribution.Package.Homepage
+	}
+
+	gitCmd := exec.Command("git", "rev-parse", "HEAD")
+	gitCmd.Dir = workdir
+
+	if output, gitErr := gitCmd.Output(); gitErr == nil { // Have a commit
+		labels["org.opencontainers.image.revision"] = strings.TrimSpace(string(output))
+	}
+
+	for label, value := range config.Labels {
+		labels[label] = value
+	}
+
+	return labels
+}
+
+// GetImageContainerConfig will return how containers of the image are run, where our Entrypoint defaults to the binary at its InstallPath
+func GetImageContainerConfig(name string, config NoodlesImageConfig, epoch time.Time) OCIContainerConfig {
+	containerConfig := OCIContainerConfig{
+		Cmd:        config.Cmd,
+		Entrypoint: config.Entrypoint,
+		Labels:     GetImageLabels(config, epoch),
+		User:       config.User,
+		WorkingDir: config.WorkingDir,
+	}
+
+	if len(containerConfig.Entrypoint) == 0 {
+		containerConfig.Entrypoint = []string{GetImageInstallPath(name, config)}
+	}
+
+	env := map[string]string{"PATH": DefaultImagePath}
+
+	for key, value := range config.Env {
+		env[key] = value
+	}
+
+	for key, value := range env {
+		containerConfig.Env = append(containerConfig.Env, key+"="+value)
+	}
+
+	sort.Strings(containerConfig.Env)
+
+	if len(config.ExposedPorts) != 0 {
+		containerConfig.ExposedPorts = make(map[string]struct{})
+
+		for _, port := range config.ExposedPorts {
+			if !strings.Contains(port, "/") { // Default to tcp, the same as Docker
+				port += "/tcp"
+			}
+
+			containerConfig.ExposedPorts[port] = struct{}{}
+		}
+	}
+
+	return containerConfig
+}
+
+// GetImageInstallPath will return the path of the project's binary within its image
+func GetImageInstallPath(name string, config NoodlesImageConfig) string {
+	if config.InstallPath != "" {
+		return path.Clean("/" + config.InstallPath)
+	}
+
+	return "/usr/local/bin/" + name
+}
+
+// WriteImageBlob will write the content to the blobs of the OCI image layout, returning its descriptor
+func WriteImageBlob(layoutDir, mediaType string, content []byte) (descriptor OCIDescriptor, writeErr error) {
+	digest := sha256.Sum256(content)
+
+	descriptor = OCIDescriptor{
+		Digest:    "sha256:" + hex.EncodeToString(digest[:]),
+		MediaType: mediaType,
+		Size:      int64(len(content)),
+	}
+
+	writeErr = ioutil.WriteFile(filepath.Join(layoutDir, "blobs", "sha256", hex.EncodeToString(digest[:])), content, 0644)
+	return
+}
+
+// WriteImageJSON will write the value as JSON to the blobs of the OCI image layout, returning its descriptor
+func WriteImageJSON(layoutDir, mediaType string, value interface{}) (descriptor OCIDescriptor, writeErr error) {
+	var content []byte
+	if content, writeErr = json.Marshal(value); writeErr != nil {
+		return
+	}
+
+	descriptor, writeErr = WriteImageBlob(layoutDir, mediaType, content)
+	return
+}
+
+// WriteImageLayer will write the gzipped layer to the blobs of the OCI image layout, returning its descriptor and uncompressed digest
+func WriteImageLayer(layoutDir string, layer []byte) (descriptor OCIDescriptor, diffID string, writeErr error) {
+	uncompressed := layer
+	compressed := layer
+
+	if bytes.HasPrefix(layer, []byte{0x1f, 0x8b}) { // Already gzipped
+		var gzipReader *gzip.Reader
+		if gzipReader, writeErr = gzip.NewReader(bytes.NewReader(layer)); writeErr != nil {
+			return
+		}
+
+		if uncompressed, writeErr = ioutil.ReadAll(gzipReader); writeErr != nil {
+			return
+		}
+	} else {
+		var buffer bytes.Buffer
+		var compressorWriter io.WriteCloser
+
+		if compressorWriter, writeErr = NewCompressor(&buffer, "gzip"); writeErr != nil {
+			return
+		}
+
+		if _, writeErr = compressorWriter.Write(layer); writeErr == nil {
+			writeErr = compressorWriter.Close()
+		}
+
+		compressed = buffer.Bytes()
+	}
+
+	if writeErr != nil {
+		return
+	}
+
+	digest := sha256.Sum256(uncompressed)
+	diffID = "sha256:" + hex.EncodeToString(digest[:])
+	descriptor, writeErr = WriteImageBlob(layoutDir, OCIImageLayerMediaType, compressed)
+	return
+}
+
+// GetImageBinaryLayer will return the uncompressed layer of our binary at its install path, along with its parent directories
+func GetImageBinaryLayer(binary, installPath string, epoch time.Time) (layer []byte, layerErr error) {
+	var info os.FileInfo
+	if info, layerErr = os.Stat(binary); layerErr != nil {
+		return
+	}
+
+	var buffer bytes.Buffer
+	tarWriter := tar.NewWriter(&buffer)
+	dirs := strings.Split(strings.Trim(path.Dir(installPath), "/"), "/")
+
+	for index := range dirs { // Each parent directory, such as usr/, usr/local/ and usr/local/bin/
+		if dirs[0] == "" { // Installed to the root
+			break
+		}
+
+		header := &tar.Header{Mode: 0755, ModTime: epoch, Name: strings.Join(dirs[:index+1], "/") + "/", Typeflag: tar.TypeDir}
+
+		if layerErr = tarWriter.WriteHeader(header); layerErr != nil {
+			return
+		}
+	}
+
+	header := &tar.Header{
+		Mode:     0755, // Always executable
+		ModTime:  GetArchiveModTime(info, epoch),
+		Name:     strings.TrimPrefix(installPath, "/"),
+		Size:     info.Size(),
+		Typeflag: tar.TypeReg,
+	}
+
+	if layerErr = tarWriter.WriteHeader(header); layerErr != nil {
+		return
+	}
+
+	if layerErr = CopyFileTo(tarWriter, binary); layerErr == nil {
+		layerErr = tarWriter.Close()
+	}
+
+	layer = buffer.Bytes()
+	return
+}
+
+// WriteImageManifest will write the layers, config and manifest of the platform's image to the OCI image layout, returning the manifest's descriptor
+func WriteImageManifest(layoutDir, name string, config NoodlesImageConfig, platform ImagePlatform, epoch time.Time) (descriptor OCIDescriptor, writeErr error) {
+	manifest := OCIManifest{
+		MediaType:     OCIImageManifestMediaType,
+		SchemaVersion: 2,
+	}
+
+	imageConfig := OCIImageConfig{
+		Architecture: platform.Arch,
+		Config:       GetImageContainerConfig(name, config, epoch),
+		Created:      epoch.Format(time.RFC3339),
+		OS:           platform.OS,
+		RootFS:       OCIRootFS{Type: "layers"},
+	}
+
+	layers := [][]byte{}
+
+	if config.BaseLayer != "" { // Have a root filesystem to add our binary to
+		baseLayerPath := strings.NewReplacer("{arch}", platform.Arch, "{os}", platform.OS).Replace(config.BaseLayer)
+
+		var baseLayer []byte
+		if baseLayer, writeErr = ioutil.ReadFile(filepath.Join(workdir, baseLayerPath)); writeErr != nil {
+			writeErr = fmt.Errorf("Failed to read the base layer for %s/%s:\n%s", platform.OS, platform.Arch, writeErr.Error())
+			return
+		}
+
+		layers = append(layers, baseLayer)
+	}
+
+	var binaryLayer []byte
+	if binaryLayer, writeErr = GetImageBinaryLayer(platform.Binary, GetImageInstallPath(name, config), epoch); writeErr != nil {
+		return
+	}
+
+	layers = append(layers, binaryLayer)
+
+	for _, layer := range layers {
+		var layerDescriptor OCIDescriptor
+		var diffID string
+
+		if layerDescriptor, diffID, writeErr = WriteImageLayer(layoutDir, layer); writeErr != nil {
+			return
+		}
+
+		manifest.Layers = append(manifest.Layers, layerDescriptor)
+		imageConfig.RootFS.DiffIDs = append(imageConfig.RootFS.DiffIDs, diffID)
+	}
+
+	if manifest.Config, writeErr = WriteImageJSON(layoutDir, OCIImageConfigMediaType, imageConfig); writeErr != nil {
+		return
+	}
+
+	if descriptor, writeErr = WriteImageJSON(layoutDir, OCIImageManifestMediaType, manifest); writeErr == nil {
+		descriptor.Platform = &OCIPlatform{Architecture: platform.Arch, OS: platform.OS}
+	}
+
+	return
+}
+
+// WriteImageLayout will write the OCI image layout of the project to the provided directory
+// When we have builds for multiple platforms, index.json references a multi-arch index of them
+func WriteImageLayout(layoutDir, name string, epoch time.Time) (writeErr error) {
+	config := noodles.Distribution.Images[name]
+
+	var platforms []ImagePlatform
+	if platforms, writeErr = GetImagePlatforms(noodles.Projects[name], config); writeErr != nil {
+		return
+	}
+
+	if writeErr = os.MkdirAll(filepath.Join(layoutDir, "blobs", "sha256"), 0755); writeErr != nil {
+		return
+	}
+
+	index := OCIIndex{
+		MediaType:     OCIImageIndexMediaType,
+		SchemaVersion: 2,
+	}
+
+	for _, platform := range platforms {
+		var descriptor OCIDescriptor
+		if descriptor, writeErr = WriteImageManifest(layoutDir, name, config, platform, epoch); writeErr != nil {
+			return
+		}
+
+		index.Manifests = append(index.Manifests, descriptor)
+	}
+
+	if len(index.Manifests) > 1 { // Multi-arch, so reference an index of every platform
+		var descriptor OCIDescriptor
+		if descriptor, writeErr = WriteImageJSON(layoutDir, OCIImageIndexMediaType, index); writeErr != nil {
+			return
+		}
+
+		index.Manifests = []OCIDescriptor{descriptor}
+	}
+
+	index.Manifests[0].Annotations = map[string]string{OCIRefNameAnnotation: noodles.Version.String()} // Tagged with our Version
+
+	var content []byte
+	if content, writeErr = json.Marshal(index); writeErr != nil {
+		return
+	}
+
+	if writeErr = ioutil.WriteFile(filepath.Join(layoutDir, "index.json"), content, 0644); writeErr == nil {
+		writeErr = ioutil.WriteFile(filepath.Join(layoutDir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644)
+	}
+
+	return
+}
+
+// WriteImageTar will write a tarball of the OCI image layout of the project, which is the same for the same builds
+func WriteImageTar(w io.Writer, name string, epoch time.Time) (writeErr error) {
+	layoutDir := filepath.Join(workdir, ".noodles-image")
+	os.RemoveAll(layoutDir) // Wipe any previous layout
+
+	defer os.RemoveAll(layoutDir)
+
+	if writeErr = WriteImageLayout(layoutDir, name, epoch); writeErr != nil {
+		return
+	}
+
+	var files []string
+	if files, writeErr = GetArchiveFiles(layoutDir); writeErr != nil {
+		return
+	}
+
+	tarWriter := tar.NewWriter(w)
+
+	for _, file := range files {
+		if writeErr = WriteTarEntry(tarWriter, layoutDir, file, epoch); writeErr != nil {
+			break
+		}
+	}
+
+	if closeErr := tarWriter.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	return
+}
+
+// CreateImage will create the OCI image layout of the project, only replacing any existing image once it is complete
+func CreateImage(name string) (imagePath string, createErr error) {
+	imagePath = GetImagePath(name)
+	partialPath := imagePath + ".partial"
+	epoch := GetSourceDateEpoch()
+	os.RemoveAll(partialPath)
+
+	if noodles.Distribution.Images[name].Layout == "dir" {
+		createErr = WriteImageLayout(partialPath, name, epoch)
+	} else {
+		var imageFile *os.File
+
+		if imageFile, createErr = os.Create(partialPath); createErr != nil { // Failed to create our tarball
+			return
+		}
+
+		createErr = WriteImageTar(imageFile, name, epoch)
+
+		if closeErr := imageFile.Close(); createErr == nil {
+			createErr = closeErr
+		}
+	}
+
+	if createErr == nil { // Complete
+		os.RemoveAll(imagePath)
+		createErr = os.Rename(partialPath, imagePath)
+	} else {
+		os.RemoveAll(partialPath)
+	}
+
+	return
+}
+
+// VerifyImage will rebuild the tarball of the project's OCI image, ensuring it matches the one we created
+func VerifyImage(name string) (verifyErr error) {
+	imagePath := GetImagePath(name)
+
+	var original string
+	if original, verifyErr = GetFileSHA256(imagePath); verifyErr != nil { // Failed to read the image we created
+		return
+	}
+
+	rebuilt := sha256.New()
+
+	if verifyErr = WriteImageTar(rebuilt, name, GetSourceDateEpoch()); verifyErr != nil { // Failed to rebuild our image
+		verifyErr = fmt.Errorf("Failed to rebuild %s:\n%s", imagePath, verifyErr.Error())
+		return
+	}
+
+	if rebuiltDigest := hex.EncodeToString(rebuilt.Sum(nil)); rebuiltDigest != original { // Differs
+		verifyErr = fmt.Errorf("%s is not reproducible: sha256 %s, rebuilt as %s", imagePath, original, rebuiltDigest)
+		return
+	}
+
+	trunk.LogSuccess("Verified " + imagePath + " (sha256 " + original + ")")
+	return
+}
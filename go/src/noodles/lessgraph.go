@@ -0,0 +1,159 @@
+package main
+
+// This file contains functionality pertaining to the @import graph of LESS projects, used to only rebuild them when needed
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// LessBuildState is what we built a LESS project from, so we know whether it needs rebuilding
+type LessBuildState struct {
+	Config string            // Hash of the project config and flags we built with
+	Files  map[string]string // Hash of every file in the import graph, by path relative to our workdir
+	Output string            // File we produced, which may be hashed
+}
+
+// LessImportGraph is the graph of @imports from the entry points of a LESS project
+type LessImportGraph struct {
+	Imports map[string][]string // Files imported by each file, in the order they are imported
+	Sources []string            // Entry points of the project
+}
+
+var pendingLessBuildStates map[string]LessBuildState // State of each project as we started compiling it, saved once it is built or kept if it failed
+
+func init() {
+	pendingLessBuildStates = make(map[string]LessBuildState)
+}
+
+// GetLessImportGraph will resolve the full graph of @imports from the project's entry points
+func (n *NoodlesProject) GetLessImportGraph() LessImportGraph {
+	graph := LessImportGraph{
+		Imports: make(map[string][]string),
+		Sources: n.GetLessSources(),
+	}
+
+	WalkLessImports(graph.Sources, n.GetLessIncludePaths(), func(file string, imports []LessImport, readErr error) {
+		graph.Imports[file] = []string{}
+
+		for _, lessImport := range imports {
+			for _, resolved := range lessImport.Resolved {
+				if !ListContainsExact(graph.Imports[file], resolved) { // Not already imported, such as by a glob
+					graph.Imports[file] = append(graph.Imports[file], resolved)
+				}
+			}
+		}
+	})
+
+	return graph
+}
+
+// Files will return every file in the graph, sorted
+func (g LessImportGraph) Files() []string {
+	files := []string{}
+
+	for file := range g.Imports {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+	return files
+}
+
+// GetLessBuildStatePath will return the path to the build state of the provided LESS project name
+func GetLessBuildStatePath(name string) string {
+	return filepath.Join(workdir, ".noodles", "less."+name+".json")
+}
+
+// GetLessBuildState will return the current build state of the project, being its config and the hash of every file it imports
+func (p *LessPlugin) GetLessBuildState(n *NoodlesProject) LessBuildState {
+	state := LessBuildState{
+		Files: make(map[string]string),
+	}
+
+	project := *n // Apply our defaults, so the config is the same before and after compiling
+	project.Destination = n.GetDefaultLessDestination()
+	project.Source = n.GetDefaultLessSource()
+
+	config, _ := json.Marshal(struct {
+		Browsers []string
+		Flags    []string
+		Project  NoodlesProject
+	}{n.GetBrowsers(), p.GetCompilerFlags(n), project})
+
+	state.Config = CreateHash(config)
+
+	for _, file := range n.GetLessImportGraph().Files() { // For each file we'd build from
+		if content, readErr := ioutil.ReadFile(file); readErr == nil {
+			state.Files[GetDiagnosticPath(file, workdir)] = CreateHash(content) // Relative to our workdir, so the workspace can move
+		}
+	}
+
+	return state
+}
+
+// Matches will return whether the config and files of both states are the same
+func (s LessBuildState) Matches(other LessBuildState) bool {
+	if s.Config != other.Config || len(s.Files) != len(other.Files) { // Config or graph changed
+		return false
+	}
+
+	for file, hash := range s.Files {
+		if other.Files[file] != hash { // File changed or newly imported
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsUpToDate will return whether none of the files the project imports, nor its config, have changed since it was last built
+// A project which failed to build during this run is also up to date until it changes, so watching doesn't retry it endlessly
+func (p *LessPlugin) IsUpToDate(n *NoodlesProject) bool {
+	if failed := pendingLessBuildStates[n.Name]; failed.Config != "" { // Failed to build during this run
+		return failed.Matches(p.GetLessBuildState(n))
+	}
+
+	var previous LessBuildState
+
+	if content, readErr := ioutil.ReadFile(GetLessBuildStatePath(n.Name)); readErr != nil || json.Unmarshal(content, &previous) != nil { // Never built, or unreadable
+		return false
+	}
+
+	if _, statErr := os.Stat(filepath.Join(workdir, previous.Output)); statErr != nil { // Output has since been removed
+		return false
+	}
+
+	return previous.Matches(p.GetLessBuildState(n))
+}
+
+// SaveLessBuildState will save the build state of the project from when we started compiling it, along with the output we produced
+func (p *LessPlugin) SaveLessBuildState(n *NoodlesProject, output string) (saveErr error) {
+	state, exists := pendingLessBuildStates[n.Name]
+
+	if !exists || state.Config == "" { // Not compiled during this run
+		return
+	}
+
+	pendingLessBuildStates[n.Name] = LessBuildState{} // No longer pending
+
+	if filepath.IsAbs(output) { // Relative to our workdir, so the workspace can move
+		output, _ = filepath.Rel(workdir, output)
+	}
+
+	state.Output = output
+
+	var content []byte
+	if content, saveErr = json.MarshalIndent(state, "", "\t"); saveErr != nil { // Failed to encode our state
+		return
+	}
+
+	if saveErr = os.MkdirAll(filepath.Dir(GetLessBuildStatePath(n.Name)), 0755); saveErr == nil { // Created our state dir
+		saveErr = ioutil.WriteFile(GetLessBuildStatePath(n.Name), append(content, '\n'), 0644)
+	}
+
+	return
+}
@@ -0,0 +1,269 @@
+package main
+
+// This file contains functionality pertaining to resolving the @imports of LESS files
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// LessImport is an @import in a LESS file
+type LessImport struct {
+	Line     int
+	Optional bool     // Imported with (optional), so it need not resolve
+	Path     string   // Path as written in the @import
+	Resolved []string // Files the import resolved to, multiple if globbed
+}
+
+// lessImportRegex matches @import statements, with their optional options, such as @import (reference) "mixins";
+var lessImportRegex = regexp.MustCompile(`@import\s*(?:\(([^)]*)\)\s*)?(?:url\(\s*)?["']([^"']+)["']`)
+
+// GetDefaultLessSource will return the Source of the project, or the Source we default to when compiling
+func (n *NoodlesProject) GetDefaultLessSource() string {
+	if n.Source == "" { // If no Source is set
+		return filepath.Join("src/less/", n.SimpleName+".less")
+	}
+
+	return n.Source
+}
+
+// GetDefaultLessDestination will return the Destination of the project, or the Destination we default to when compiling
+func (n *NoodlesProject) GetDefaultLessDestination() string {
+	if n.Destination == "" { // If no Destination is set
+		return filepath.Join("build", n.SimpleName+".css")
+	}
+
+	return n.Destination
+}
+
+// GetLessIncludePaths will return the --include-path directories from the project's Flags, relative to our workdir
+func (n *NoodlesProject) GetLessIncludePaths() []string {
+	includePaths := []string{}
+
+	for index, flag := range n.Flags { // For each flag
+		var paths string
+
+		if strings.HasPrefix(flag, "--include-path=") { // --include-path=dir
+			paths = strings.TrimPrefix(flag, "--include-path=")
+		} else if flag == "--include-path" && index+1 < len(n.Flags) { // --include-path dir
+			paths = n.Flags[index+1]
+		}
+
+		for _, path := range filepath.SplitList(paths) { // lessc separates paths like PATH does
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(workdir, path)
+			}
+
+			includePaths = append(includePaths, path)
+		}
+	}
+
+	return includePaths
+}
+
+// GetLessSources will return the absolute paths to the entry points of the project, expanding a globbed Source
+func (n *NoodlesProject) GetLessSources() []string {
+	source := filepath.Join(workdir, n.GetDefaultLessSource())
+
+	if strings.Contains(source, "*") { // Globbed source
+		return GlobFiles(source)
+	}
+
+	if _, statErr := os.Stat(source); statErr != nil { // Source does not exist
+		return []string{}
+	}
+
+	return []string{source}
+}
+
+// GlobFiles will return the files matching the provided pattern, which may use ** to match any number of directories
+func GlobFiles(pattern string) []string {
+	files := []string{}
+
+	if !strings.Contains(pattern, "**") { // Standard glob
+		matches, _ := filepath.Glob(pattern)
+
+		for _, match := range matches {
+			if info, statErr := os.Stat(match); statErr == nil && !info.IsDir() {
+				files = append(files, match)
+			}
+		}
+
+		return files
+	}
+
+	root := pattern[:strings.Index(pattern, "*")] // Walk from the directory before our first wildcard
+	root = root[:strings.LastIndex(root, string(filepath.Separator))+1]
+
+	matcher := GlobRegexp(pattern)
+
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && matcher.MatchString(filepath.ToSlash(path)) {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files
+}
+
+// GlobRegexp will return a regular expression matching the provided pattern, where ** matches any number of directories
+func GlobRegexp(pattern string) *regexp.Regexp {
+	expression := regexp.QuoteMeta(filepath.ToSlash(pattern))
+	expression = strings.Replace(expression, `\*\*/`, `(.*/)?`, -1)
+	expression = strings.Replace(expression, `\*\*`, `.*`, -1)
+	expression = strings.Replace(expression, `\*`, `[^/]*`, -1)
+	expression = strings.Replace(expression, `\?`, `[^/]`, -1)
+
+	return regexp.MustCompile("^" + expression + "$")
+}
+
+// StripLessComments will replace the comments in the provided LESS with spaces, leaving strings and line numbers untouched
+func StripLessComments(content []byte) []byte {
+	stripped := make([]byte, len(content))
+	copy(stripped, content)
+
+	var quote byte
+
+	for i := 0; i < len(stripped); i++ {
+		c := stripped[i]
+
+		if quote != 0 { // Inside of a string
+			if c == '\\' { // Escaped character
+				i++
+			} else if c == quote || c == '\n' { // End of string
+				quote = 0
+			}
+
+			continue
+		}
+
+		if c == '"' || c == '\'' { // Start of string
+			quote = c
+		} else if c == '/' && i+1 < len(stripped) && stripped[i+1] == '/' { // Line comment
+			for ; i < len(stripped) && stripped[i] != '\n'; i++ {
+				stripped[i] = ' '
+			}
+		} else if c == '/' && i+1 < len(stripped) && stripped[i+1] == '*' { // Block comment
+			end := len(stripped) // Unterminated comments run to the end of the file
+
+			if commentEnd := bytes.Index(stripped[i+2:], []byte("*/")); commentEnd != -1 {
+				end = i + 2 + commentEnd + 2
+			}
+
+			for ; i < end; i++ {
+				if stripped[i] != '\n' { // Keep our line numbers
+					stripped[i] = ' '
+				}
+			}
+
+			i-- // Resume at the end of the comment
+		}
+	}
+
+	return stripped
+}
+
+// IsPlainCSSImport will return whether lessc leaves the import as is for the browser, rather than importing it
+func IsPlainCSSImport(path string, options []string) bool {
+	if ListContainsExact(options, "less") || ListContainsExact(options, "inline") { // Forced to be read
+		return false
+	}
+
+	if ListContainsExact(options, "css") { // Forced to be left as is
+		return true
+	}
+
+	return strings.HasPrefix(path, "//") || strings.Contains(path, "://") || strings.HasSuffix(strings.SplitN(path, "?", 2)[0], ".css")
+}
+
+// ResolveLessImport will resolve the import path from the importing file, trying its directory followed by our include paths
+func ResolveLessImport(importer, path string, includePaths []string) []string {
+	if filepath.Ext(path) == "" { // lessc assumes .less
+		path += ".less"
+	}
+
+	dirs := append([]string{filepath.Dir(importer)}, includePaths...)
+
+	for _, dir := range dirs { // For each directory we should resolve against
+		candidate := path
+
+		if !filepath.IsAbs(candidate) {
+			candidate = filepath.Join(dir, path)
+		}
+
+		if strings.Contains(candidate, "*") { // Globbed import, using less-plugin-glob
+			if matches := GlobFiles(candidate); len(matches) != 0 {
+				return matches
+			}
+		} else if info, statErr := os.Stat(candidate); statErr == nil && !info.IsDir() { // Exists
+			return []string{candidate}
+		}
+	}
+
+	return []string{}
+}
+
+// WalkLessImports will call walkFunc for each of the provided sources and every file they import, once per file
+func WalkLessImports(sources []string, includePaths []string, walkFunc func(file string, imports []LessImport, readErr error)) {
+	walked := make(map[string]bool)
+	pending := append([]string{}, sources...)
+
+	for len(pending) != 0 { // Follow our imports through every file they import
+		file := pending[0]
+		pending = pending[1:]
+
+		if walked[file] { // Already walked, likely imported multiple times
+			continue
+		}
+
+		walked[file] = true
+		imports, readErr := GetLessImports(file, includePaths)
+		walkFunc(file, imports, readErr)
+
+		for _, lessImport := range imports {
+			pending = append(pending, lessImport.Resolved...)
+		}
+	}
+}
+
+// GetLessImports will return the imports of the provided LESS file that lessc will read, resolved against our include paths
+func GetLessImports(file string, includePaths []string) (imports []LessImport, readErr error) {
+	var content []byte
+
+	if content, readErr = ioutil.ReadFile(file); readErr != nil { // Failed to read the file
+		return
+	}
+
+	content = StripLessComments(content)
+
+	for _, location := range lessImportRegex.FindAllSubmatchIndex(content, -1) { // For each import
+		options := []string{}
+
+		if location[2] != -1 { // Have options
+			for _, option := range strings.Split(string(content[location[2]:location[3]]), ",") {
+				options = append(options, strings.TrimSpace(option))
+			}
+		}
+
+		path := string(content[location[4]:location[5]])
+
+		if IsPlainCSSImport(path, options) { // Left for the browser
+			continue
+		}
+
+		imports = append(imports, LessImport{
+			Line:     bytes.Count(content[:location[0]], []byte("\n")) + 1,
+			Optional: ListContainsExact(options, "optional"),
+			Path:     path,
+			Resolved: ResolveLessImport(file, path, includePaths),
+		})
+	}
+
+	return
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/JoshStrobl/trunk"
 	"github.com/spf13/cobra"
+	"os"
 )
 
 var lintCmd = &cobra.Command{
@@ -14,11 +15,14 @@ var lintCmd = &cobra.Command{
 	DisableAutoGenTag: true,
 }
 
+var lintFailed bool // Whether any project failed linting, so we exit with a failure status
 var minimumConfidence float64
+var lintFix bool
 var lintProject string
 
 func init() {
 	lintCmd.Flags().Float64VarP(&minimumConfidence, "confidence", "c", 0.5, "Minimum confidence for linting problems")
+	lintCmd.Flags().BoolVarP(&lintFix, "fix", "f", false, "Automatically fix problems where the linter supports it")
 	lintCmd.Flags().StringVarP(&lintProject, "project", "p", "", "Name of the project we're linting")
 }
 
@@ -30,6 +34,10 @@ func lint(cmd *cobra.Command, args []string) {
 	} else { // If a specific project is set
 		LintProject(lintProject)
 	}
+
+	if lintFailed { // Problems or errors while linting
+		os.Exit(1)
+	}
 }
 
 // LintProject is responsible for running the respective linters for each project's type
@@ -54,12 +62,14 @@ func LintProject(name string) {
 
 		if preRunErr != nil { // If there was an error during pre-run
 			trunk.LogErrRaw(fmt.Errorf("An error occurred during pre-run checks:\n%s\n", preRunErr.Error()))
+			lintFailed = true
 			return
 		}
 
 		lintErr := plugin.Lint(&project, minimumConfidence)
 
 		if lintErr != nil {
+			lintFailed = true
 			trunk.LogErrRaw(fmt.Errorf("An error occurred during linting:\n%s\n", lintErr.Error()))
 
 			if project.Plugin != "go" { // If this isn't Go, where it's absolutely mandatory to do a GOPATH reset
@@ -77,5 +87,6 @@ func LintProject(name string) {
 		}
 	} else {
 		trunk.LogErr(name + " is not a valid project")
+		lintFailed = true
 	}
 }
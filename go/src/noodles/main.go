@@ -25,6 +25,10 @@ var rootCmd = &cobra.Command{
 	- compilation of project(s) in a configurable, ordered manner
 	- configurable packing of project assets for distribution`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if diagnosticsFormat != DiagnosticsFormatPretty && diagnosticsFormat != DiagnosticsFormatProblemMatcher { // Not a format we render
+			trunk.LogFatal("--diagnostics-format must be one of: " + DiagnosticsFormatPretty + "," + DiagnosticsFormatProblemMatcher)
+		}
+
 		if cmd.Use != "new" || (cmd.Use == "new" && (newProjectName != "") || (newScriptName != "")) { // If we're not creating a new Noodles workspace
 			if conf, readErr := ReadConfig(filepath.Join(workdir, "noodles.toml")); readErr == nil { // Read the config
 				noodles = conf
@@ -46,15 +50,23 @@ func init() {
 		trunk.LogFatal("Failed to get the current working directory: " + getWdErr.Error())
 	}
 
+	rootCmd.PersistentFlags().StringVar(&diagnosticsFormat, "diagnostics-format", DiagnosticsFormatPretty, "Format of compiler and linter diagnostics: "+DiagnosticsFormatPretty+" or "+DiagnosticsFormatProblemMatcher+" (file:line:col: severity code: message)")
+
 	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(changelogCmd)
 	rootCmd.AddCommand(checkCmd)
 	rootCmd.AddCommand(genDocs)
+	rootCmd.AddCommand(graphCmd)
 	rootCmd.AddCommand(lintCmd)
 	rootCmd.AddCommand(newCmd)
 	rootCmd.AddCommand(packCmd)
+	rootCmd.AddCommand(publishCmd)
 	rootCmd.AddCommand(setupCmd)
 	rootCmd.AddCommand(scriptCmd)
+	rootCmd.AddCommand(testCmd)
 	rootCmd.AddCommand(tidyCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(versionCmd)
 }
 
 func main() {
@@ -0,0 +1,284 @@
+package main
+
+// This file contains functionality pertaining to signing and verifying files with minisign compatible ed25519 keys
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MinisignPublicKey is a minisign public key
+type MinisignPublicKey struct {
+	KeyID     [8]byte
+	PublicKey ed25519.PublicKey
+}
+
+// MinisignSecretKey is a decrypted minisign secret key
+type MinisignSecretKey struct {
+	KeyID      [8]byte
+	PrivateKey ed25519.PrivateKey
+}
+
+// MinisignSignature is a parsed .minisig file
+type MinisignSignature struct {
+	Algorithm       string // Ed for the file itself, ED for its BLAKE2b-512 hash
+	GlobalSignature []byte // Signature of Signature and TrustedComment
+	KeyID           [8]byte
+	Signature       []byte
+	TrustedComment  string
+}
+
+const MinisignSignatureExtension = ".minisig"
+
+// GetSigningKeyPath will return the path to our minisign secret key, from NOODLES_SIGNING_KEY or Distribution SigningKey
+func GetSigningKeyPath() string {
+	if path := os.Getenv("NOODLES_SIGNING_KEY"); path != "" { // Set by the environment
+		return path
+	}
+
+	return noodles.Distribution.SigningKey
+}
+
+// GetPublicKeyPath will return the path to our minisign public key, from NOODLES_PUBLIC_KEY or Distribution PublicKey
+func GetPublicKeyPath() string {
+	if path := os.Getenv("NOODLES_PUBLIC_KEY"); path != "" { // Set by the environment
+		return path
+	}
+
+	return noodles.Distribution.PublicKey
+}
+
+// FormatMinisignKeyID will return the key ID as minisign shows it
+func FormatMinisignKeyID(keyID [8]byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(keyID[:]))
+}
+
+// ReadMinisignFile will return the untrusted comment and the decoded lines of a minisign key or signature file
+func ReadMinisignFile(path string) (untrustedComment string, lines [][]byte, readErr error) {
+	var content []byte
+	if content, readErr = ioutil.ReadFile(path); readErr != nil {
+		return
+	}
+
+	for index, line := range strings.Split(strings.TrimSpace(strings.Replace(string(content), "\r\n", "\n", -1)), "\n") {
+		if index == 0 { // Untrusted comment
+			if !strings.HasPrefix(line, "untrusted comment: ") {
+				readErr = errors.New(path + " is not a minisign file")
+				return
+			}
+
+			untrustedComment = strings.TrimPrefix(line, "untrusted comment: ")
+		} else if strings.HasPrefix(line, "trusted comment: ") { // Kept as is, since it is signed
+			lines = append(lines, []byte(strings.TrimPrefix(line, "trusted comment: ")))
+		} else {
+			var decoded []byte
+			if decoded, readErr = base64.StdEncoding.DecodeString(strings.TrimSpace(line)); readErr != nil {
+				readErr = errors.New(path + " is not a minisign file: " + readErr.Error())
+				return
+			}
+
+			lines = append(lines, decoded)
+		}
+	}
+
+	if len(lines) == 0 {
+		readErr = errors.New(path + " is not a minisign file")
+	}
+
+	return
+}
+
+// ReadMinisignPublicKey will read the minisign public key at the provided path
+func ReadMinisignPublicKey(path string) (key MinisignPublicKey, readErr error) {
+	var lines [][]byte
+	if _, lines, readErr = ReadMinisignFile(path); readErr != nil {
+		return
+	}
+
+	if len(lines[0]) != 42 || string(lines[0][:2]) != "Ed" { // Algorithm, key ID and public key
+		readErr = errors.New(path + " is not a minisign public key")
+		return
+	}
+
+	copy(key.KeyID[:], lines[0][2:10])
+	key.PublicKey = ed25519.PublicKey(lines[0][10:])
+	return
+}
+
+// ReadMinisignSecretKey will read and, if it is encrypted, decrypt the minisign secret key at the provided path
+func ReadMinisignSecretKey(path string, getPassword func() (string, error)) (key MinisignSecretKey, readErr error) {
+	var lines [][]byte
+	if _, lines, readErr = ReadMinisignFile(path); readErr != nil {
+		return
+	}
+
+	raw := lines[0]
+
+	if len(raw) != 158 || string(raw[:2]) != "Ed" || string(raw[4:6]) != "B2" { // Algorithm, KDF, checksum algorithm, KDF parameters and the key itself
+		readErr = errors.New(path + " is not a minisign secret key")
+		return
+	}
+
+	kdf := string(raw[2:4])
+	secret := append([]byte{}, raw[54:]...) // Key ID, secret key and checksum
+
+	if kdf == "Sc" { // Encrypted with scrypt
+		var password string
+		if password, readErr = getPassword(); readErr != nil {
+			return
+		}
+
+		logN, r, p := GetScryptParameters(binary.LittleEndian.Uint64(raw[38:46]), binary.LittleEndian.Uint64(raw[46:54]))
+
+		var stream []byte
+		if stream, readErr = scrypt.Key([]byte(password), raw[6:38], 1<<logN, r, p, len(secret)); readErr != nil {
+			return
+		}
+
+		for i := range secret {
+			secret[i] ^= stream[i]
+		}
+	} else if kdf != "\x00\x00" { // Not unencrypted either
+		readErr = errors.New(path + " uses an unsupported key derivation function")
+		return
+	}
+
+	checksum := blake2b.Sum256(append(append([]byte{}, raw[:2]...), secret[:72]...))
+
+	if !bytes.Equal(checksum[:], secret[72:]) { // Wrong password, or corrupted
+		readErr = errors.New("failed to decrypt " + path + ": incorrect password or corrupted key")
+		return
+	}
+
+	copy(key.KeyID[:], secret[:8])
+	key.PrivateKey = ed25519.PrivateKey(secret[8:72])
+	return
+}
+
+// GetScryptParameters will return the scrypt parameters for the provided limits, the same way libsodium does for minisign
+func GetScryptParameters(opsLimit, memLimit uint64) (logN uint, r, p int) {
+	if opsLimit < 32768 {
+		opsLimit = 32768
+	}
+
+	r = 8
+
+	if opsLimit < memLimit/32 {
+		p = 1
+		maxN := opsLimit / uint64(r*4)
+
+		for logN = 1; logN < 63; logN++ {
+			if uint64(1)<<logN > maxN/2 {
+				break
+			}
+		}
+	} else {
+		maxN := memLimit / uint64(r*128)
+
+		for logN = 1; logN < 63; logN++ {
+			if uint64(1)<<logN > maxN/2 {
+				break
+			}
+		}
+
+		maxRP := (opsLimit / 4) / (uint64(1) << logN)
+
+		if maxRP > 0x3fffffff {
+			maxRP = 0x3fffffff
+		}
+
+		p = int(maxRP) / r
+	}
+
+	return
+}
+
+// SignMinisign will sign the file at the provided path, writing the signature alongside it as a .minisig
+func SignMinisign(path string, key MinisignSecretKey) (signErr error) {
+	var content []byte
+	if content, signErr = ioutil.ReadFile(path); signErr != nil {
+		return
+	}
+
+	hash := blake2b.Sum512(content)
+	signature := ed25519.Sign(key.PrivateKey, hash[:])
+	trustedComment := fmt.Sprintf("timestamp:%d\tfile:%s\thashed", GetSourceDateEpoch().Unix(), filepath.Base(path)) // Same timestamp as our archive contents, so signatures are reproducible too
+	globalSignature := ed25519.Sign(key.PrivateKey, append(append([]byte{}, signature...), trustedComment...))
+
+	sigLine := append(append([]byte("ED"), key.KeyID[:]...), signature...)
+
+	minisig := "untrusted comment: signature from noodles secret key " + FormatMinisignKeyID(key.KeyID) + "\n"
+	minisig += base64.StdEncoding.EncodeToString(sigLine) + "\n"
+	minisig += "trusted comment: " + trustedComment + "\n"
+	minisig += base64.StdEncoding.EncodeToString(globalSignature) + "\n"
+
+	signErr = ioutil.WriteFile(path+MinisignSignatureExtension, []byte(minisig), 0644)
+	return
+}
+
+// ReadMinisignSignature will read the minisign signature at the provided path
+func ReadMinisignSignature(path string) (signature MinisignSignature, readErr error) {
+	var lines [][]byte
+	if _, lines, readErr = ReadMinisignFile(path); readErr != nil {
+		return
+	}
+
+	if len(lines) != 3 || len(lines[0]) != 74 || len(lines[2]) != ed25519.SignatureSize { // Signature, trusted comment and global signature
+		readErr = errors.New(path + " is not a minisign signature")
+		return
+	}
+
+	signature.Algorithm = string(lines[0][:2])
+	copy(signature.KeyID[:], lines[0][2:10])
+	signature.Signature = lines[0][10:]
+	signature.TrustedComment = string(lines[1])
+	signature.GlobalSignature = lines[2]
+
+	return
+}
+
+// VerifyMinisign will verify the signature of the file at the provided path, being its .minisig, against the public key
+func VerifyMinisign(path string, key MinisignPublicKey) (verifyErr error) {
+	var signature MinisignSignature
+	if signature, verifyErr = ReadMinisignSignature(path + MinisignSignatureExtension); verifyErr != nil {
+		return
+	}
+
+	if signature.KeyID != key.KeyID { // Signed by another key
+		verifyErr = fmt.Errorf("%s was signed with key %s, not %s", filepath.Base(path), FormatMinisignKeyID(signature.KeyID), FormatMinisignKeyID(key.KeyID))
+		return
+	}
+
+	var content []byte
+	if content, verifyErr = ioutil.ReadFile(path); verifyErr != nil {
+		return
+	}
+
+	switch signature.Algorithm {
+	case "ED": // Signed the BLAKE2b-512 hash of the file
+		hash := blake2b.Sum512(content)
+		content = hash[:]
+	case "Ed": // Legacy, signed the file itself
+	default:
+		verifyErr = errors.New(filepath.Base(path) + MinisignSignatureExtension + " uses an unsupported signature algorithm")
+		return
+	}
+
+	if !ed25519.Verify(key.PublicKey, content, signature.Signature) { // File does not match the signature
+		verifyErr = errors.New("signature of " + filepath.Base(path) + " is invalid")
+	} else if !ed25519.Verify(key.PublicKey, append(append([]byte{}, signature.Signature...), signature.TrustedComment...), signature.GlobalSignature) { // Trusted comment was tampered with
+		verifyErr = errors.New("trusted comment of " + filepath.Base(path) + MinisignSignatureExtension + " is invalid")
+	}
+
+	return
+}
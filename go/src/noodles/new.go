@@ -8,7 +8,6 @@ import (
 	"github.com/stroblindustries/coreutils"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 )
 
@@ -86,13 +85,7 @@ func NewWorkspacePrompt() {
 			}
 		} else {
 			validate = func(input string) error {
-				var err error
-				_, convErr := strconv.ParseFloat(input, 64) // Attempt to just do a conversion
-
-				if convErr != nil {
-					err = errors.New("invalid version number")
-				}
-
+				_, err := ParseSemanticVersion(input) // Must be a semantic version, such as 0.1.0
 				return err
 			}
 		}
@@ -105,8 +98,8 @@ func NewWorkspacePrompt() {
 	noodles.Description = promptProperties["description"]
 	noodles.License = promptProperties["license"]
 
-	version, _ := strconv.ParseFloat(promptProperties["version"], 64) // Convert the version to a proper num
-	noodles.Version = version
+	version, _ := ParseSemanticVersion(promptProperties["version"]) // Convert the version to a proper semantic version
+	noodles.Version = NoodlesVersion{SemanticVersion: version}
 
 	if saveErr := SaveConfig(); saveErr == nil { // Save the config
 		trunk.LogSuccess("Noodles workspace created.")
@@ -184,6 +177,10 @@ func GoProjectPrompt(plugin string, name string, project *NoodlesProject) {
 	enableGoModules := TextPromptValidate("Enable Go Modules [y/N]", TextYNValidate)
 	project.EnableGoModules = IsYes(enableGoModules)
 
+	if project.EnableGoModules { // If we're using Go Modules, allow a module other than the one in the workspace root
+		project.ModuleDir = coreutils.InputMessage("Go Module directory (default is noodles root directory)")
+	}
+
 	consolidateChildDirs := TextPromptValidate("Enable nested directories [y/N]", TextYNValidate)
 	project.ConsolidateChildDirs = IsYes(consolidateChildDirs)
 
@@ -195,6 +192,17 @@ func GoProjectPrompt(plugin string, name string, project *NoodlesProject) {
 func LESSProjectPrompt(project *NoodlesProject) {
 	appendHashVal := TextPromptValidate("Append SHA256SUM to end of file name [y/N]", TextYNValidate)
 	project.AppendHash = IsYes(appendHashVal)
+
+	autoprefixVal := TextPromptValidate("Add vendor prefixes with autoprefixer [y/N]", TextYNValidate)
+	project.Autoprefix = IsYes(autoprefixVal)
+
+	if project.Autoprefix { // Allow browsers specific to this project
+		if browsers := coreutils.InputMessage("Browsers, comma separated (default is workspace Browsers)"); browsers != "" {
+			for _, browser := range strings.Split(browsers, ",") {
+				project.Browsers = append(project.Browsers, strings.TrimSpace(browser))
+			}
+		}
+	}
 }
 
 // SourceDestinationPrompt prompts for the sources and destinations for compilation
@@ -229,13 +237,15 @@ func TypeScriptProjectPrompt(project *NoodlesProject) {
 
 	targetPrompt := promptui.Select{
 		Label: "Target",
-		Items: ValidTypeScriptTargets,
+		Items: typescriptPlugin.GetValidTargets(),
 	}
 
 	_, targetPromptVal, targetPromptErr := targetPrompt.Run()
 	PromptErrorCheck(targetPromptErr)
 
 	project.Target = targetPromptVal
+
+	project.TSConfig = coreutils.InputMessage("Path to tsconfig.json (optional, generated from the above if empty)")
 }
 
 // NewScriptPrompt will handle the necessary script creation prompts
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/JoshStrobl/trunk"
+	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
-	"github.com/stroblindustries/coreutils"
+	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
 )
 
@@ -16,30 +19,31 @@ var tmpDir string
 var packCmd = &cobra.Command{
 	Use:               "pack",
 	Short:             "Package configured assets for all or a specified project",
-	Long:              "Package configured assets for all or a specified project into a distributable tarball",
+	Long:              "Package configured assets for all or a specified project into a distributable tarball or zip",
 	Run:               pack,
 	DisableAutoGenTag: true,
 }
 
+var packPerProject bool
 var packProject string
+var packList bool
+var packSourceMaps bool
+var packVerify bool
 
 func init() {
 	tmpDir = filepath.Join(workdir, ".noodles-pack")
+	packCmd.Flags().BoolVarP(&packList, "list", "l", false, "List the files we would pack and their paths within our archives, without packing them")
+	packCmd.Flags().BoolVarP(&packPerProject, "per-project", "", false, "Also create an archive for each project, regardless of Distribution ProjectArchives")
 	packCmd.Flags().StringVarP(&packProject, "project", "p", "", "Name of a project we're packing")
+	packCmd.Flags().BoolVarP(&packSourceMaps, "source-maps", "m", false, "Include source maps, regardless of Distribution IncludeSourceMaps")
+	packCmd.Flags().BoolVarP(&packVerify, "verify", "", false, "Repack our assets and verify the archives, packages and image tarballs are byte-identical")
 }
 
-// pack will package configured assets for a specified project into a tarball
+// pack will package configured assets for a specified project into reproducible archives
 func pack(cmd *cobra.Command, args []string) {
-	if !coreutils.ExecutableExists("tar") { // Tar not on system
-		trunk.LogFatal("tar does not exist on the system.")
-	}
-
-	os.RemoveAll(tmpDir) // Wipe our tmpDir
-
 	var projectsToPack map[string]NoodlesProject
 
 	if packProject == "" {
-		trunk.LogInfo("Started packing.")
 		projectsToPack = noodles.Projects
 	} else {
 		projectsToPack = map[string]NoodlesProject{
@@ -47,29 +51,170 @@ func pack(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	if creationErr := os.Mkdir(tmpDir, 0755); creationErr != nil {
-		trunk.LogErrRaw(fmt.Errorf("Failed to create our temporary directory:\n%s", creationErr.Error()))
+	if packList { // Only show what we would pack
+		ListPackFiles(projectsToPack)
 		return
 	}
 
-	for projectName, project := range projectsToPack { // For each project
-		trunk.LogInfo("Packing " + projectName)
+	if packProject == "" {
+		trunk.LogInfo("Started packing.")
+	}
+
+	if outputErr := os.MkdirAll(GetOutputDir(), 0755); outputErr != nil {
+		trunk.LogErrRaw(fmt.Errorf("Failed to create our output directory:\n%s", outputErr.Error()))
+		os.Exit(1)
+	}
+
+	if noodles.Distribution.Changelog != "" { // Generate the changelog we pack
+		if changelogErr := WriteChangelogFile(); changelogErr != nil {
+			trunk.LogErrRaw(fmt.Errorf("Failed to generate the changelog:\n%s", changelogErr.Error()))
+			os.Exit(1)
+		}
+	}
+
+	archives := []string{}
+	archiveNames := make(map[string]bool)
+
+	for index, archive := range GetPackArchives(projectsToPack) { // For our combined archive and any project archives
+		if archiveNames[archive.Name] { // Would replace one of our archives
+			trunk.LogFatal("Multiple archives would be named " + archive.Name + ". Change Distribution ProjectArchiveName.")
+		}
+
+		archiveNames[archive.Name] = true
+
+		if stageErr := StagePackFiles(archive.Projects, index == 0); stageErr != nil { // Only log our projects once
+			trunk.LogErrRaw(stageErr)
+			os.Exit(1)
+		}
+
+		created, tarErr := TarContents(archive.Name)
+		archives = append(archives, created...)
+
+		if tarErr != nil {
+			trunk.LogErrRaw(tarErr)
+			os.Exit(1)
+		}
+
+		if packVerify { // Rebuild our archives and ensure they are identical
+			if verifyErr := VerifyArchives(archive); verifyErr != nil {
+				trunk.LogErrRaw(verifyErr)
+				os.Exit(1)
+			}
+		}
+	}
+
+	for _, format := range noodles.Distribution.PackageFormats { // For each package we create
+		packagePath, pathErr := GetPackagePath(projectsToPack, format)
+
+		if pathErr != nil {
+			trunk.LogErrRaw(pathErr)
+			os.Exit(1)
+		}
+
+		trunk.LogInfo("Creating " + packagePath)
+
+		if _, packageErr := CreatePackage(projectsToPack, format); packageErr != nil {
+			trunk.LogErrRaw(fmt.Errorf("Failed to create %s:\n%s", packagePath, packageErr.Error()))
+			os.Exit(1)
+		}
+
+		archives = append(archives, packagePath)
+
+		if packVerify { // Rebuild our package and ensure it is identical
+			if verifyErr := VerifyPackage(projectsToPack, format); verifyErr != nil {
+				trunk.LogErrRaw(verifyErr)
+				os.Exit(1)
+			}
+		}
+	}
+
+	for _, name := range GetImageNames(projectsToPack) { // For each OCI image we create
+		imagePath := GetImagePath(name)
+		trunk.LogInfo("Creating " + imagePath)
+
+		if _, imageErr := CreateImage(name); imageErr != nil {
+			trunk.LogErrRaw(fmt.Errorf("Failed to create %s:\n%s", imagePath, imageErr.Error()))
+			os.Exit(1)
+		}
+
+		if noodles.Distribution.Images[name].Layout == "dir" { // Only tarballs are checksummed and signed
+			continue
+		}
+
+		archives = append(archives, imagePath)
+
+		if packVerify { // Rebuild our image and ensure it is identical
+			if verifyErr := VerifyImage(name); verifyErr != nil {
+				trunk.LogErrRaw(verifyErr)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if checksumErr := WriteChecksums(archives); checksumErr != nil {
+		trunk.LogErrRaw(fmt.Errorf("Failed to write %s:\n%s", ChecksumsFile, checksumErr.Error()))
+		os.Exit(1)
+	}
+
+	if signErr := SignArchives(archives); signErr != nil {
+		trunk.LogErrRaw(signErr)
+		os.Exit(1)
+	}
+}
+
+// GetPackFiles will return the files of the provided projects, at their TarballLocation, and of our Distribution Include, sorted by their path within our archives
+func GetPackFiles(projectsToPack map[string]NoodlesProject, verbose bool) (packFiles []PackFile, packErr error) {
+	names := []string{}
+
+	for projectName := range projectsToPack {
+		names = append(names, projectName)
+	}
+
+	sort.Strings(names)
+
+	for _, projectName := range names { // For each project
+		project := projectsToPack[projectName]
+
+		if verbose {
+			trunk.LogInfo("Packing " + projectName)
+		}
+
+		if project.Plugin == "go" && project.Type == "package" { // Packages are compiled rather than producing an artifact
+			if verbose {
+				trunk.LogInfo(projectName + " is a Go package, so has nothing to pack")
+			}
+
+			continue
+		}
 
 		if project.Plugin != "" { // If a plugin is defined
-			projectDestFolder := filepath.Dir(project.Destination)
-			fileName := filepath.Base(project.Destination)
+			destination := project.GetDestination() // Including the Destination we default to
+			projectDestFolder := filepath.Dir(destination)
+			fileName := filepath.Base(destination)
 			fileNameNoExt := strings.TrimSuffix(fileName, filepath.Ext(fileName))
 
 			files := []string{fileName} // Have an array of files we should copy, at minimum the specified fileName
 
+			if project.Plugin == "typescript" && project.IsTypeScriptModule() { // Destination is an output directory of modules
+				projectDestFolder = destination
+				files = GetPackableModuleFiles(destination)
+			}
+
 			if project.TarballLocation == "" { // If no tarball location
-				trunk.LogWarn("No tarball location has been set for this project. We'll attempt to place this in a smart place.")
+				if verbose {
+					trunk.LogWarn("No tarball location has been set for this project. We'll attempt to place this in a smart place.")
+				}
 
 				switch project.Plugin {
 				case "less":
 					project.TarballLocation = "css/"
 				case "typescript":
 					project.TarballLocation = "js/"
+
+					if project.IsTypeScriptModule() { // Already have every emitted file
+						break
+					}
+
 					files = append(files, fileNameNoExt+".d.ts") // Add the definition file
 
 					if project.Compress {
@@ -78,49 +223,244 @@ func pack(cmd *cobra.Command, args []string) {
 				}
 			}
 
+			if project.AppendHash && (project.Plugin == "less" || (project.Plugin == "typescript" && !project.IsTypeScriptModule())) { // PostRun renamed our output to include its hash
+				hashable := fileName
+
+				if project.Plugin == "typescript" && project.Compress { // The hash is appended to our minified file
+					hashable = fileNameNoExt + ".min.js"
+				}
+
+				for index, file := range files {
+					if file != hashable { // Not renamed
+						continue
+					}
+
+					if hashed := GetHashedFileName(projectDestFolder, file); hashed != "" {
+						files[index] = hashed
+					}
+				}
+			}
+
+			if project.SourceMaps && (noodles.Distribution.IncludeSourceMaps || packSourceMaps) { // If we should include the source maps of our files
+				for _, file := range files {
+					if _, statErr := os.Stat(filepath.Join(projectDestFolder, GetSourceMapPath(file))); statErr == nil { // Has a source map
+						files = append(files, GetSourceMapPath(file))
+					}
+				}
+			}
+
 			for _, file := range files {
-				CopyFile(filepath.Join(projectDestFolder, file), filepath.Join(tmpDir, project.TarballLocation, file)) // Copy this specific file
+				source := filepath.Join(projectDestFolder, file)
+
+				if _, statErr := os.Stat(source); statErr != nil { // Not built
+					packErr = fmt.Errorf("Failed to pack %s:\n%s does not exist", projectName, source)
+					return
+				}
+
+				packFiles = append(packFiles, PackFile{
+					Path:   filepath.ToSlash(filepath.Join(project.TarballLocation, file)),
+					Source: source,
+				})
 			}
 		}
 	}
 
-	TarContents()
+	var includes []PackFile
+	if includes, packErr = GetDistributionIncludes(); packErr != nil { // Failed to resolve our Include
+		return
+	}
+
+	packFiles = append(packFiles, includes...)
+
+	if noodles.Distribution.Changelog != "" { // Generated by pack
+		packFiles = append(packFiles, PackFile{
+			Path:   strings.TrimPrefix(filepath.ToSlash(filepath.Clean(noodles.Distribution.Changelog)), "/"),
+			Source: GetChangelogSourcePath(),
+		})
+	}
+
+	filtered := []PackFile{}
+	sources := make(map[string]string) // Source of each path, so we can catch multiple files packed to the same path
+
+	for _, file := range packFiles {
+		if IsDistributionExcluded(file) { // Never packed
+			continue
+		}
+
+		if existing, exists := sources[file.Path]; exists && existing != file.Source { // Two files want the same path
+			packErr = fmt.Errorf("Both %s and %s would be packed as %s", existing, file.Source, file.Path)
+			return
+		} else if !exists {
+			sources[file.Path] = file.Source
+			filtered = append(filtered, file)
+		}
+	}
+
+	sort.Slice(filtered, func(a, b int) bool { return filtered[a].Path < filtered[b].Path })
+	packFiles = filtered
+
+	return
 }
 
-// TarContents will create a tar file out of the contents of our temporary directory and save it to the corresponding .tar file
-func TarContents() {
-	noodlesCondensedName := strings.ToLower(noodles.Name)                                         // Lowercase the workspace name
-	noodlesCondensedName = strings.Replace(strings.TrimSpace(noodlesCondensedName), " ", "_", -1) // Trim whitespace and replace rest with _
+// StagePackFiles will copy the files we pack into our temporary directory
+func StagePackFiles(projectsToPack map[string]NoodlesProject, verbose bool) (stageErr error) {
+	var packFiles []PackFile
+	if packFiles, stageErr = GetPackFiles(projectsToPack, verbose); stageErr != nil {
+		return
+	}
 
-	version := strconv.FormatFloat(noodles.Version, 'f', -1, 64) // Convert our float64 noodles.Version to a version string
+	os.RemoveAll(tmpDir) // Wipe our tmpDir
 
-	for _, compressor := range noodles.Distribution.TarCompressors { // For each compressor
-		tarName := noodlesCondensedName + "-" + version + ".tar" // Create our initial tarball name
+	if stageErr = os.Mkdir(tmpDir, 0755); stageErr != nil {
+		stageErr = fmt.Errorf("Failed to create our temporary directory:\n%s", stageErr.Error())
+		return
+	}
 
-		switch compressor {
-		case "bzip2": // bzip2 doesn't use .bzip2
-			tarName += ".bz2" // Use .tar.bz2
-		case "gzip": // gzip doesn't use .gzip
-			tarName += ".gz" // Use .tar.gz
-		case "lzma": // lzma
-			tarName += ".lzma" // Use .tar.lzma
-		case "xz": // xz
-			tarName += ".xz" // Use .tar.xz
-		case "zstd": // zstd doesn't use .zstd
-			tarName += ".zst" // Use .tar.zst
+	for _, file := range packFiles {
+		if copyErr := CopyFile(file.Source, filepath.Join(tmpDir, filepath.FromSlash(file.Path))); copyErr != nil { // Copy this specific file
+			stageErr = fmt.Errorf("Failed to pack %s:\n%s", file.Path, copyErr.Error())
+			return
+		}
+	}
+
+	return
+}
+
+// ListPackFiles will print the path within our archives of each file we would pack, along with where it is copied from
+// When we also create project archives, the files of each archive follow its name
+func ListPackFiles(projectsToPack map[string]NoodlesProject) {
+	packArchives := GetPackArchives(projectsToPack)
+
+	for _, archive := range packArchives {
+		packFiles, packErr := GetPackFiles(archive.Projects, false)
+
+		if packErr != nil {
+			trunk.LogErrRaw(packErr)
+			os.Exit(1)
+		}
+
+		indent := ""
+
+		if len(packArchives) > 1 { // Show which archive the files are in
+			fmt.Println(archive.Name + ":")
+			indent = "\t"
 		}
 
-		tarArgs := []string{
-			"-C",    // Change to the directory so we don't have the leading directory paths
-			tmpDir,  // The directory we're compressing
-			"-a",    // Auto compress based on archive suffix
-			"-c",    // Create tar archive
-			"-f",    // Specify file
-			tarName, // Must specify tar name after -f
-			".",     // Current directory, which is our tmpDir after changing
+		for _, file := range packFiles {
+			source := file.Source
+
+			if noodles.Distribution.Changelog != "" && source == GetChangelogSourcePath() { // Only written once we pack, so may not exist yet
+				source += " (generated)"
+			}
+
+			fmt.Printf("%s%s\t%s\n", indent, file.Path, source)
+		}
+	}
+}
+
+// GetPackableModuleFiles will return the files, relative to the output directory, of a TypeScript module project that should be packed
+func GetPackableModuleFiles(dir string) []string {
+	files := []string{}
+
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && !strings.HasSuffix(path, ".map") && !strings.HasSuffix(path, ".tsbuildinfo") { // Source maps are added separately
+			relPath, _ := filepath.Rel(dir, path)
+			files = append(files, relPath)
 		}
 
+		return nil
+	})
+
+	return files
+}
+
+// TarContents will create an archive with the provided name out of the contents of our temporary directory for each of our distribution formats
+func TarContents(name string) (archives []string, tarErr error) {
+	for _, compressor := range noodles.Distribution.TarCompressors { // For each compressor, or zip
+		tarName := GetArchivePath(name, compressor)
 		trunk.LogInfo("Creating " + tarName)
-		coreutils.ExecCommand("tar", tarArgs, true)
+
+		if tarErr = CreateArchive(tmpDir, tarName, compressor); tarErr != nil { // Failed to create our archive
+			tarErr = fmt.Errorf("Failed to create %s:\n%s", tarName, tarErr.Error())
+			return
+		}
+
+		archives = append(archives, tarName)
+	}
+
+	return
+}
+
+// SignArchives will sign each of the provided archives with our signing key, if we have one
+func SignArchives(archives []string) (signErr error) {
+	keyPath := GetSigningKeyPath()
+
+	if keyPath == "" { // Signing isn't configured
+		return
+	}
+
+	var key MinisignSecretKey
+	if key, signErr = ReadMinisignSecretKey(keyPath, GetSigningKeyPassword); signErr != nil { // Failed to read our key
+		signErr = fmt.Errorf("Failed to read the signing key:\n%s", signErr.Error())
+		return
+	}
+
+	for _, archive := range archives {
+		trunk.LogInfo("Signing " + archive)
+
+		if signErr = SignMinisign(archive, key); signErr != nil {
+			signErr = fmt.Errorf("Failed to sign %s:\n%s", archive, signErr.Error())
+			return
+		}
+	}
+
+	return
+}
+
+// GetSigningKeyPassword will return the password of our signing key from NOODLES_SIGNING_KEY_PASSWORD, otherwise prompting for it
+func GetSigningKeyPassword() (string, error) {
+	if password, exists := os.LookupEnv("NOODLES_SIGNING_KEY_PASSWORD"); exists { // Set by the environment
+		return password, nil
+	}
+
+	passwordPrompt := promptui.Prompt{
+		Label: "Signing key password",
+		Mask:  '*',
+	}
+
+	return passwordPrompt.Run()
+}
+
+// VerifyArchives will copy the files of the archive again and rebuild it in each format, ensuring it matches the one we created
+func VerifyArchives(archive PackArchive) (verifyErr error) {
+	if verifyErr = StagePackFiles(archive.Projects, false); verifyErr != nil { // Failed to copy our files again
+		return
+	}
+
+	for _, compressor := range noodles.Distribution.TarCompressors { // For each compressor, or zip
+		tarName := GetArchivePath(archive.Name, compressor)
+
+		var content []byte
+		if content, verifyErr = ioutil.ReadFile(tarName); verifyErr != nil { // Failed to read the archive we created
+			return
+		}
+
+		rebuilt := sha256.New()
+
+		if verifyErr = WriteArchive(rebuilt, tmpDir, compressor); verifyErr != nil { // Failed to rebuild our archive
+			verifyErr = fmt.Errorf("Failed to rebuild %s:\n%s", tarName, verifyErr.Error())
+			return
+		}
+
+		original := sha256.Sum256(content)
+
+		if rebuiltDigest := hex.EncodeToString(rebuilt.Sum(nil)); rebuiltDigest != hex.EncodeToString(original[:]) { // Differs
+			verifyErr = fmt.Errorf("%s is not reproducible: sha256 %s, rebuilt as %s", tarName, hex.EncodeToString(original[:]), rebuiltDigest)
+			return
+		}
+
+		trunk.LogSuccess("Verified " + tarName + " (sha256 " + hex.EncodeToString(original[:]) + ")")
 	}
+
+	return
 }
@@ -0,0 +1,414 @@
+package main
+
+// This file contains functionality pertaining to the .deb and .rpm packages we create of our workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/JoshStrobl/trunk"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PackageFile is a file we install with our packages
+type PackageFile struct {
+	Config  bool // Configuration the user may change, which is kept across upgrades
+	Mode    os.FileMode
+	ModTime time.Time // Clamped to our epoch, the same as our archives
+	Path    string    // Absolute path the file is installed to
+	Size    int64
+	Source  string // Path to the file, relative to our workdir
+}
+
+// PackageInfo is everything needed to create one of our packages
+type PackageInfo struct {
+	Arch        string // Architecture, as the package format names it
+	Depends     []PackageDependency
+	Description string
+	Files       []PackageFile
+	Homepage    string
+	License     string
+	Maintainer  string
+	Name        string
+	Release     string
+	Scripts     map[string]string // Maintainer scripts by when they run, being PreInstall, PostInstall, PreRemove or PostRemove
+	Version     string            // Version, with pre-releases using ~ so they sort before their release
+}
+
+// PackageDependency is a package we depend on, optionally constrained to a version
+type PackageDependency struct {
+	Name     string
+	Operator string // One of <, <=, =, >= or >
+	Version  string
+}
+
+// packageDependencyRegex matches dependencies such as libc6, libc6 >= 2.28 and libc6 (>= 2.28)
+var packageDependencyRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9+._-]*)\s*(?:\(?\s*(<<|>>|<=|>=|=|<|>)\s*([0-9A-Za-z][^\s()]*)\s*\)?)?$`)
+
+// packageNameRegex matches the names both Debian and Fedora allow for packages
+var packageNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]+$`)
+
+// debArchitectures are the Debian names of GOARCH values which differ from them
+var debArchitectures = map[string]string{
+	"386":     "i386",
+	"arm":     "armhf",
+	"ppc64le": "ppc64el",
+}
+
+// rpmArchitectures are the Fedora names of GOARCH values which differ from them
+var rpmArchitectures = map[string]string{
+	"386":   "i686",
+	"amd64": "x86_64",
+	"arm":   "armv7hl",
+	"arm64": "aarch64",
+}
+
+// GetPackageName will return the name of our packages, from Package Name or our workspace Name
+func GetPackageName() string {
+	if noodles.Distribution.Package.Name != "" {
+		return noodles.Distribution.Package.Name
+	}
+
+	return strings.Replace(CondenseArchiveName(noodles.Name), "_", "-", -1) // Neither allow _
+}
+
+// GetPackageRelease will return the Release of our packages, defaulting to 1
+func GetPackageRelease() string {
+	if noodles.Distribution.Package.Release != "" {
+		return noodles.Distribution.Package.Release
+	}
+
+	return "1"
+}
+
+// GetPackageVersion will return our Version as packages use it, where pre-releases use ~ so 2.0.0~rc.1 sorts before 2.0.0
+func GetPackageVersion() string {
+	version := noodles.Version.SemanticVersion
+	packageVersion := fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+
+	if len(version.PreRelease) != 0 {
+		packageVersion += "~" + strings.Join(version.PreRelease, ".")
+	}
+
+	if version.Build != "" {
+		packageVersion += "+" + version.Build
+	}
+
+	return packageVersion
+}
+
+// GetPackageArch will return the architecture of our packages in the provided format, being that of our Go binaries and plugins if we have any
+func GetPackageArch(projectsToPack map[string]NoodlesProject, format string) string {
+	platformSpecific := false
+
+	for _, project := range projectsToPack {
+		if project.Plugin == "go" && project.Type != "package" { // Only runs on the platform it was built for
+			platformSpecific = true
+		}
+	}
+
+	_, goarch := GetGoPlatform()
+
+	switch format {
+	case "deb":
+		if !platformSpecific {
+			return "all"
+		} else if arch, exists := debArchitectures[goarch]; exists {
+			return arch
+		}
+	case "rpm":
+		if !platformSpecific {
+			return "noarch"
+		} else if arch, exists := rpmArchitectures[goarch]; exists {
+			return arch
+		}
+	}
+
+	return goarch
+}
+
+// GetPackagePath will return the path to our package in the provided format, named the way its format does
+func GetPackagePath(projectsToPack map[string]NoodlesProject, format string) (packagePath string, pathErr error) {
+	var name string
+
+	switch format {
+	case "deb": // Such as noodles_0.1.0-1_amd64.deb
+		name = GetPackageName() + "_" + GetPackageVersion() + "-" + GetPackageRelease() + "_" + GetPackageArch(projectsToPack, format) + ".deb"
+	case "rpm": // Such as noodles-0.1.0-1.x86_64.rpm
+		name = GetPackageName() + "-" + GetPackageVersion() + "-" + GetPackageRelease() + "." + GetPackageArch(projectsToPack, format) + ".rpm"
+	default:
+		pathErr = errors.New("Unsupported package format: " + format)
+		return
+	}
+
+	packagePath = filepath.Join(GetOutputDir(), name)
+	return
+}
+
+// ParsePackageDependency will parse a dependency such as libc6 >= 2.28
+func ParsePackageDependency(dependency string) (parsed PackageDependency, parseErr error) {
+	matches := packageDependencyRegex.FindStringSubmatch(strings.TrimSpace(dependency))
+
+	if matches == nil {
+		parseErr = errors.New(dependency + " is not a valid dependency, such as libc6 or libc6 >= 2.28")
+		return
+	}
+
+	parsed.Name = matches[1]
+	parsed.Operator = strings.Replace(strings.Replace(matches[2], "<<", "<", 1), ">>", ">", 1) // Debian's strict operators
+	parsed.Version = matches[3]
+
+	return
+}
+
+// MatchInstallPath will return the path of the file relative to the InstallPaths pattern it matches, if it does
+func MatchInstallPath(pattern, file string) (relPath string, matches bool) {
+	pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+
+	if strings.ContainsAny(pattern, "*?") { // Glob, relative to the directory before its first wildcard
+		if matches = GlobRegexp(pattern).MatchString(file); matches {
+			base := filepath.ToSlash(GetGlobBase(pattern))
+			relPath = strings.TrimPrefix(file, base+"/")
+		}
+	} else if file == pattern { // File
+		relPath, matches = path.Base(file), true
+	} else if strings.HasPrefix(file, pattern+"/") { // Within the directory
+		relPath, matches = strings.TrimPrefix(file, pattern+"/"), true
+	}
+
+	return
+}
+
+// GetPackageFiles will return the files of the provided projects and our Distribution Include at the path they are installed to, along with our ConfigFiles
+func GetPackageFiles(projectsToPack map[string]NoodlesProject, epoch time.Time) (files []PackageFile, packageErr error) {
+	var packFiles []PackFile
+	if packFiles, packageErr = GetPackFiles(projectsToPack, false); packageErr != nil {
+		return
+	}
+
+	config := noodles.Distribution.Package
+	installDir := config.InstallDir
+
+	if installDir == "" {
+		installDir = "/usr/share/" + GetPackageName()
+	}
+
+	patterns := []string{}
+
+	for pattern := range config.InstallPaths {
+		patterns = append(patterns, pattern)
+	}
+
+	sort.Strings(patterns)
+
+	for _, file := range packFiles {
+		installPath := path.Join(installDir, file.Path)
+
+		for _, pattern := range patterns { // The first pattern, sorted, that the file matches
+			if relPath, matches := MatchInstallPath(pattern, file.Path); matches {
+				installPath = path.Join(config.InstallPaths[pattern], relPath)
+				break
+			}
+		}
+
+		files = append(files, PackageFile{Path: installPath, Source: file.Source})
+	}
+
+	for source, installPath := range config.ConfigFiles {
+		files = append(files, PackageFile{Config: true, Path: path.Clean(installPath), Source: source})
+	}
+
+	installed := make(map[string]string)
+
+	for index, file := range files {
+		if !path.IsAbs(file.Path) { // Must be absolute
+			packageErr = errors.New(file.Source + " would be installed to " + file.Path + ", which is not an absolute path")
+			return
+		}
+
+		if existing, exists := installed[file.Path]; exists { // Two files want the same path
+			packageErr = fmt.Errorf("Both %s and %s would be installed to %s", existing, file.Source, file.Path)
+			return
+		}
+
+		installed[file.Path] = file.Source
+
+		var info os.FileInfo
+		if info, packageErr = os.Stat(file.Source); packageErr != nil { // Such as a missing ConfigFiles entry
+			return
+		} else if !info.Mode().IsRegular() { // Such as a directory
+			packageErr = errors.New(file.Source + " is not a regular file")
+			return
+		}
+
+		files[index].Mode = GetArchiveMode(info)
+		files[index].ModTime = GetArchiveModTime(info, epoch)
+		files[index].Size = info.Size()
+	}
+
+	sort.Slice(files, func(a, b int) bool { return files[a].Path < files[b].Path })
+	return
+}
+
+// GetPackageInfo will return everything needed to create our package of the provided projects in the provided format
+func GetPackageInfo(projectsToPack map[string]NoodlesProject, format string, epoch time.Time) (info PackageInfo, infoErr error) {
+	config := noodles.Distribution.Package
+
+	info = PackageInfo{
+		Arch:        GetPackageArch(projectsToPack, format),
+		Description: noodles.Description,
+		Homepage:    config.Homepage,
+		License:     noodles.License,
+		Maintainer:  config.Maintainer,
+		Name:        GetPackageName(),
+		Release:     GetPackageRelease(),
+		Scripts:     make(map[string]string),
+		Version:     GetPackageVersion(),
+	}
+
+	if !packageNameRegex.MatchString(info.Name) {
+		infoErr = errors.New(info.Name + " is not a valid package name. Set Distribution Package Name to a lowercase name, such as my-app")
+		return
+	}
+
+	if info.Description == "" { // Both formats require a summary
+		info.Description = info.Name
+	}
+
+	if format == "deb" && info.Maintainer == "" {
+		infoErr = errors.New("Distribution Package Maintainer must be set to create a .deb, such as \"Jane Doe <jane@example.com>\"")
+		return
+	}
+
+	dependencies := append([]string{}, config.Depends...)
+
+	if format == "deb" {
+		dependencies = append(dependencies, config.DebDepends...)
+	} else {
+		dependencies = append(dependencies, config.RPMDepends...)
+	}
+
+	for _, dependency := range dependencies {
+		var parsed PackageDependency
+		if parsed, infoErr = ParsePackageDependency(dependency); infoErr != nil {
+			return
+		}
+
+		info.Depends = append(info.Depends, parsed)
+	}
+
+	scripts := map[string]string{
+		"PostInstall": config.PostInstall,
+		"PostRemove":  config.PostRemove,
+		"PreInstall":  config.PreInstall,
+		"PreRemove":   config.PreRemove,
+	}
+
+	for name, scriptPath := range scripts {
+		if scriptPath == "" { // Not set
+			continue
+		}
+
+		var content []byte
+		if content, infoErr = ioutil.ReadFile(filepath.Join(workdir, scriptPath)); infoErr != nil {
+			infoErr = errors.New("Failed to read the " + name + " script: " + infoErr.Error())
+			return
+		}
+
+		info.Scripts[name] = string(content)
+	}
+
+	info.Files, infoErr = GetPackageFiles(projectsToPack, epoch)
+	return
+}
+
+// CreatePackage will create our package of the provided projects in the provided format
+func CreatePackage(projectsToPack map[string]NoodlesProject, format string) (packagePath string, createErr error) {
+	if packagePath, createErr = GetPackagePath(projectsToPack, format); createErr != nil {
+		return
+	}
+
+	epoch := GetSourceDateEpoch()
+
+	var info PackageInfo
+	if info, createErr = GetPackageInfo(projectsToPack, format, epoch); createErr != nil {
+		return
+	}
+
+	partialPath := packagePath + ".partial"
+	var packageFile *os.File
+
+	if packageFile, createErr = os.Create(partialPath); createErr != nil { // Failed to create our package
+		return
+	}
+
+	createErr = WritePackage(packageFile, info, format, epoch)
+
+	if closeErr := packageFile.Close(); createErr == nil {
+		createErr = closeErr
+	}
+
+	if createErr == nil { // Complete
+		createErr = os.Rename(partialPath, packagePath)
+	} else {
+		os.Remove(partialPath)
+	}
+
+	return
+}
+
+// WritePackage will write our package in the provided format
+func WritePackage(w io.Writer, info PackageInfo, format string, epoch time.Time) error {
+	switch format {
+	case "deb":
+		return WriteDeb(w, info, epoch)
+	case "rpm":
+		return WriteRPM(w, info, epoch)
+	default:
+		return errors.New("Unsupported package format: " + format)
+	}
+}
+
+// VerifyPackage will rebuild our package in the provided format, ensuring it matches the one we created
+func VerifyPackage(projectsToPack map[string]NoodlesProject, format string) (verifyErr error) {
+	var packagePath string
+	if packagePath, verifyErr = GetPackagePath(projectsToPack, format); verifyErr != nil {
+		return
+	}
+
+	epoch := GetSourceDateEpoch()
+
+	var original string
+	if original, verifyErr = GetFileSHA256(packagePath); verifyErr != nil { // Failed to read the package we created
+		return
+	}
+
+	var info PackageInfo
+	if info, verifyErr = GetPackageInfo(projectsToPack, format, epoch); verifyErr != nil {
+		return
+	}
+
+	rebuilt := sha256.New()
+
+	if verifyErr = WritePackage(rebuilt, info, format, epoch); verifyErr != nil { // Failed to rebuild our package
+		verifyErr = fmt.Errorf("Failed to rebuild %s:\n%s", packagePath, verifyErr.Error())
+		return
+	}
+
+	if rebuiltDigest := hex.EncodeToString(rebuilt.Sum(nil)); rebuiltDigest != original { // Differs
+		verifyErr = fmt.Errorf("%s is not reproducible: sha256 %s, rebuilt as %s", packagePath, original, rebuiltDigest)
+		return
+	}
+
+	trunk.LogSuccess("Verified " + packagePath + " (sha256 " + original + ")")
+	return
+}
@@ -31,6 +31,7 @@ func init() {
 func (p *GoPlugin) Check(n *NoodlesProject) NoodlesCheckResult {
 	results := make(NoodlesCheckResult)
 	recommendations := []string{}
+	warnings := []string{}
 
 	if n.Type == "" { // No type designated
 		recommendations = append(recommendations, "Not setting any type. Will default to binary. Recommend statically setting this.")
@@ -40,10 +41,42 @@ func (p *GoPlugin) Check(n *NoodlesProject) NoodlesCheckResult {
 		recommendations = append(recommendations, "Not using globbing for getting all Go files in this project. Recommend changing Sources to *.go.")
 	}
 
+	if n.ModuleDir != "" && !n.EnableGoModules { // Module directory set without Go Modules
+		recommendations = append(recommendations, "ModuleDir is set but Go Modules are not enabled. Recommend enabling EnableGoModules.")
+	}
+
+	if n.EnableGoModules && UsesGoWorkspace() { // Part of a multi-module workspace
+		if missing, readErr := GetMissingGoWorkUses(); readErr == nil { // Read our go.work
+			if len(missing) != 0 { // Modules missing from the workspace
+				warnings = append(warnings, "The following modules are missing from "+GoWorkFile+": "+strings.Join(missing, ", ")+". Run noodles tidy to update it.")
+			}
+		} else if os.IsNotExist(readErr) { // No go.work yet
+			warnings = append(warnings, GoWorkFile+" does not exist. Run noodles tidy to generate it.")
+		} else {
+			warnings = append(warnings, "Failed to read "+GoWorkFile+": "+readErr.Error())
+		}
+
+		if _, statErr := os.Stat(filepath.Join(n.GetModuleDir(), "go.mod")); os.IsNotExist(statErr) { // No go.mod in the module dir
+			warnings = append(warnings, "No go.mod exists in "+n.GetModuleDir()+".")
+		}
+	}
+
+	if len(n.MinifierFlags) != 0 { // Nothing to minify
+		recommendations = append(recommendations, "MinifierFlags are not used by Go projects. Recommend removing MinifierFlags.")
+	}
+
+	if conflicting := GetConflictingFlagsErrors("Flags", n.Flags, GoReservedFlags); len(conflicting) != 0 { // Flags conflicting with our own
+		results["Errors"] = conflicting
+	}
+
 	if len(recommendations) != 0 {
 		results["Recommendations"] = recommendations
 	}
 
+	if len(warnings) != 0 {
+		results["Warnings"] = warnings
+	}
+
 	return results
 }
 
@@ -69,8 +102,8 @@ func (p *GoPlugin) ConsolidateFiles(n *NoodlesProject) (consolidateErr error) {
 	}
 
 	if n.EnableGoModules && !n.DisableNestedEnvironment { // If we've enabled Go Modules and have not disabled the use of our nested environment
-		coreutils.ExecCommand("go", []string{"mod", "download"}, false) // Ensure we've pre-cached the modules before changing them
-		pkgModPath := filepath.Join(workdir, "go", "pkg", "mod")        // Set up our mod path
+		ExecGoCommand(n.GetModuleDir(), []string{"mod", "download"}) // Ensure we've pre-cached the modules before changing them
+		pkgModPath := filepath.Join(workdir, "go", "pkg", "mod")     // Set up our mod path
 
 		var nestedNoodleWorkspacesFilesList []string
 		if nestedNoodleWorkspacesFilesList, consolidateErr = coreutils.GetFilesContainsRecursive(pkgModPath, "noodles.toml"); consolidateErr != nil { // Check for any directory which has noodles.toml
@@ -91,7 +124,7 @@ func (p *GoPlugin) ConsolidateFiles(n *NoodlesProject) (consolidateErr error) {
 			repoName := filepath.Base(dir) // Get the repo name
 
 			if flattenErr := p.Flatten(repoName, dir, dir, n.ExcludeItems); flattenErr != nil { // Flatten this Noodles Workspace
-				fmt.Printf("Failed to flatten %s: %s\n", flattenErr)
+				fmt.Printf("Failed to flatten %s: %s\n", repoName, flattenErr)
 			}
 		}
 	}
@@ -192,6 +225,8 @@ func (p *GoPlugin) GetCorrectGoFilePath(n *NoodlesProject, fileName string) stri
 // Lint will lint our Go code. Lint takes a Noodles Project and the minimum acceptable confidence
 func (p *GoPlugin) Lint(n *NoodlesProject, confidence float64) error {
 	var lintErr error
+	var diagnostics []Diagnostic
+	currentDir, _ := os.Getwd()
 
 	goFiles, getErr := coreutils.GetFilesContains(n.SourceDir, ".go") // Get all files with .go extension
 
@@ -209,13 +244,14 @@ func (p *GoPlugin) Lint(n *NoodlesProject, confidence float64) error {
 						if len(problems) > 0 {
 							for _, problem := range problems { // For each problem
 								if problem.Confidence >= confidence { // If the linting confidence is equal to or greater than our requested minimum confidence
-									file := CleanupGoCompilerOutput(fileName)
-									text := CleanupGoCompilerOutput(problem.LineText)
-
-									// Example: test.go:24:34: test = errors.New("Hello world.")
-									// error strings should not be capitalized or end with punctuation or a newline
-									lineErr := strings.TrimSpace(text) // Trim any spacing
-									fmt.Printf("%s:%d:%d: %s\n%s\n", file, problem.Position.Line, problem.Position.Column, lineErr, problem.Text)
+									diagnostics = append(diagnostics, Diagnostic{
+										Code:     problem.Category,
+										Column:   problem.Position.Column,
+										File:     GetDiagnosticPath(MapFlattenedPath(fileName), currentDir),
+										Line:     problem.Position.Line,
+										Message:  problem.Text,
+										Severity: "warning",
+									})
 								}
 							}
 						}
@@ -233,35 +269,45 @@ func (p *GoPlugin) Lint(n *NoodlesProject, confidence float64) error {
 		lintErr = errors.New("failed to get files: " + getErr.Error())
 	}
 
+	PrintDiagnostics(diagnostics) // Print what we found, even if we failed partway through
 	return lintErr
 }
 
-// ModInit will ensure our Go Modules is initted if we don't have a go.mod already
+// ModInit will ensure our Go Modules is initted in the project's module directory if we don't have a go.mod already
 func (p *GoPlugin) ModInit(n *NoodlesProject) {
 	if !n.EnableGoModules { // Go Modules not enabled
 		return
 	}
 
-	modFile, modOpenErr := os.Open("go.mod")
+	moduleDir := n.GetModuleDir()
+	modFile, modOpenErr := os.Open(filepath.Join(moduleDir, "go.mod"))
 	defer modFile.Close()
 
 	if modOpenErr != nil { // Failed to open file
 		if os.IsPermission(modOpenErr) { // Permission error opening file
 			trunk.LogErrRaw(modOpenErr)
 		} else if os.IsNotExist(modOpenErr) { // File doesn't exist
-			coreutils.ExecCommand("go", []string{"mod", "init"}, false) // Run go mod init
+			if initErr := ExecGoCommand(moduleDir, []string{"mod", "init"}); initErr != nil { // Run go mod init
+				trunk.LogErrRaw(initErr)
+			}
 		}
 	}
 }
 
-// Tidy will tidy up Go Modules
+// ModTidy will tidy up Go Modules in the project's module directory and ensure it is part of our go.work
 func (p *GoPlugin) ModTidy(n *NoodlesProject) {
 	if !n.EnableGoModules {
 		trunk.LogErr(n.SimpleName + " does not have Go Modules Enabled")
 		return
 	}
 
-	coreutils.ExecCommand("go", []string{"mod", "tidy"}, false) // Run go mod tidy to remove unused deps
+	if tidyErr := ExecGoCommand(n.GetModuleDir(), []string{"mod", "tidy"}); tidyErr != nil { // Run go mod tidy to remove unused deps
+		trunk.LogErrRaw(tidyErr)
+	}
+
+	if syncErr := SyncGoWork(); syncErr != nil { // Failed to update our go.work
+		trunk.LogErrRaw(syncErr)
+	}
 }
 
 // PreRun will check if the necessary Go executable is installed
@@ -277,6 +323,12 @@ func (p *GoPlugin) PreRun(n *NoodlesProject) (preRunErr error) {
 
 	p.ModInit(n) // Mod Init if necessary
 
+	if n.EnableGoModules { // Ensure our go.work covers this module, if we're using one
+		if preRunErr = SyncGoWork(); preRunErr != nil {
+			return
+		}
+	}
+
 	if !n.DisableNestedEnvironment { // If nested environment isn't disabled
 		if preRunErr = ToggleGoEnv(true); preRunErr != nil { // Failed to toggle go environment
 			return
@@ -339,20 +391,10 @@ func (p *GoPlugin) RequiresPostRun(n *NoodlesProject) error {
 
 // Run will compile the provided project
 func (p *GoPlugin) Run(n *NoodlesProject) (runErr error) {
-	if n.Destination == "" { // If a destination is not set
-		if n.Type == "binary" { // If this is a binary
-			n.Destination = filepath.Join(workdir, "build", n.SimpleName) // Set destination to build/name (as binary)
-		} else if n.Type == "package" { // Package
-			n.Destination = workdir
-		} else if n.Type == "plugin" { // Plugin
-			n.Destination = filepath.Join(workdir, "build", n.SimpleName, ".so") // Set destination to build/name.so
-		}
-	} else {
-		if (n.Type == "plugin") && (filepath.Ext(n.Destination) != ".so") { // Destination does not have .so
-			n.Destination = n.Destination + ".so"
-		}
+	n.Destination = n.GetDestination() // Resolve our destination, defaulting to build/name for binaries
 
-		n.Destination = filepath.Join(workdir, n.Destination) // Combine workdir and destination
+	if runErr = ValidateFlags("Flags", n.Flags, GoReservedFlags); runErr != nil { // Flags conflicting with our own
+		return
 	}
 
 	if n.Type != "package" { // Binary or plugin
@@ -379,14 +421,27 @@ func (p *GoPlugin) Run(n *NoodlesProject) (runErr error) {
 			args = append(args, []string{"-buildmode", "plugin"}...)
 		}
 
+		args = append(args, n.Flags...) // Pass through our project's compiler flags
+
+		if n.ModuleDir != "" { // Building from within the module directory, so files must not be relative to our current directory
+			for index, file := range files {
+				files[index], _ = filepath.Abs(file)
+			}
+		}
+
 		args = append(args, []string{"-o", n.Destination}...)
 		args = append(args, files...)
 	} else if n.Type == "package" && !n.DisableNestedEnvironment { // Package and we're using a nested env
+		args = append(args, n.Flags...)   // Pass through our project's compiler flags
 		args = append(args, n.SimpleName) // Append the simple name of the package since that's what our GOPATH will recognize
 	}
 
 	builder := exec.Command("go", args...) // Create an os/exec command for go building
 
+	if n.ModuleDir != "" { // Module directory declared
+		builder.Dir = n.GetModuleDir() // Build within the right module
+	}
+
 	stderr, pipeErr := builder.StderrPipe()
 	stdout, outErr := builder.StdoutPipe()
 
@@ -409,7 +464,14 @@ func (p *GoPlugin) Run(n *NoodlesProject) (runErr error) {
 	stdoutOutput, _ := ioutil.ReadAll(stdout) // Read from stdout
 
 	if len(stderrOutput) != 0 { // Have stderr content
-		return errors.New(CleanupGoCompilerOutput(string(stderrOutput[:]))) // Return an error that is our stderr content
+		baseDir := builder.Dir // Paths in go's output are relative to where it ran
+
+		if baseDir == "" { // Ran in our current directory
+			baseDir, _ = os.Getwd()
+		}
+
+		output := string(stderrOutput[:])
+		return NewDiagnosticsError(ParseGoDiagnostics(output, baseDir), CleanupGoCompilerOutput(output)) // Return an error of our parsed diagnostics, or our stderr content
 	} else if len(stdoutOutput) != 0 && debug { // Have stdout content and debugging
 		trunk.LogDebug(CleanupGoCompilerOutput(string(stdoutOutput[:])))
 	}
@@ -421,6 +483,27 @@ func (p *GoPlugin) Run(n *NoodlesProject) (runErr error) {
 	return runErr
 }
 
+// Test will run go test for every package in the project's module, or its package when not using Go Modules
+func (p *GoPlugin) Test(n *NoodlesProject) (testErr error) {
+	tester := exec.Command("go", "test", "./...")
+	tester.Dir = n.GetModuleDir()
+
+	if !n.EnableGoModules { // Only our package is within our GOPATH
+		currentDir, _ := os.Getwd()
+		tester = exec.Command("go", "test", ".")
+		tester.Dir = filepath.Join(currentDir, n.SourceDir)
+	}
+
+	tester.Stdout = os.Stdout
+	tester.Stderr = os.Stderr
+
+	if runErr := tester.Run(); runErr != nil { // Tests failed or could not run
+		testErr = errors.New("go test failed: " + runErr.Error())
+	}
+
+	return
+}
+
 // CleanupGoCompilerOutput will handle the cleanup of any strings that would otherwise result from ConsolidateChildDirs
 func CleanupGoCompilerOutput(output string) string {
 	output = strings.TrimSpace(output)              // Trim space
@@ -2,11 +2,14 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"github.com/JoshStrobl/trunk"
 	"github.com/stroblindustries/coreutils"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -15,7 +18,7 @@ type LessPlugin struct {
 	CompilerFlags []string
 }
 
-// LessCompilerFlags are the flags we pass to lessc
+// LessCompilerFlags are the default flags we pass to lessc
 var LessCompilerFlags []string
 
 func init() {
@@ -24,40 +27,205 @@ func init() {
 		"--glob",
 		"--no-color",
 	}
+
+	lessPlugin.CompilerFlags = LessCompilerFlags
 }
 
 // Check will check the specified project's settings related to our plugin
 func (p *LessPlugin) Check(n *NoodlesProject) NoodlesCheckResult {
 	results := make(NoodlesCheckResult)
+
+	errors := GetConflictingFlagsErrors("Flags", n.Flags, LessReservedFlags)
+	recommendations := []string{}
+	warnings := []string{}
+
+	sources := n.GetLessSources()
+
+	if strings.Contains(n.GetDefaultLessSource(), "*") && len(sources) == 0 { // Globbed source without any matches
+		errors = append(errors, "Source "+n.GetDefaultLessSource()+" does not match any files.")
+	} else if len(sources) == 0 { // Source does not exist
+		errors = append(errors, "Source "+n.GetDefaultLessSource()+" does not exist.")
+	}
+
+	if n.Destination != "" && filepath.Ext(n.Destination) != ".css" { // Not a stylesheet
+		errors = append(errors, "Destination must end in .css.")
+	}
+
+	errors = append(errors, p.CheckImports(n, sources)...)
+
+	if len(n.MinifierFlags) != 0 { // lessc minifies through --clean-css
+		recommendations = append(recommendations, "MinifierFlags are not used by LESS projects. Recommend passing clean-css options through Flags instead.")
+	}
+
+	if len(n.Browsers) != 0 && !n.Autoprefix { // Browsers only matter to autoprefixer
+		recommendations = append(recommendations, "Browsers are set but Autoprefix is not enabled, so no vendor prefixes will be added. Recommend enabling Autoprefix.")
+	}
+
+	if n.AppendHash { // Our output file will be renamed
+		for _, reference := range p.GetUnhashedReferences(n) {
+			warnings = append(warnings, "AppendHash is enabled but "+reference+" refers to the unhashed "+filepath.Base(n.Destination)+", which will not exist after building.")
+		}
+	}
+
+	results["Errors"] = errors
+	results["Recommendations"] = recommendations
+	results["Warnings"] = warnings
+
 	return results
 }
 
-// Lint will lint our LESS
-func (p *LessPlugin) Lint(n *NoodlesProject, confidence float64) error {
-	if n.Source == "" { // If no Source is set
-		n.Source = filepath.Join("src/less/", n.SimpleName+".less")
+// CheckImports will return errors for any @import, in the provided sources or the files they import, which does not resolve
+func (p *LessPlugin) CheckImports(n *NoodlesProject, sources []string) []string {
+	errors := []string{}
+
+	WalkLessImports(sources, n.GetLessIncludePaths(), func(file string, imports []LessImport, readErr error) {
+		if readErr != nil { // Failed to read the file
+			errors = append(errors, "Failed to read "+GetDiagnosticPath(file, workdir)+": "+readErr.Error())
+			return
+		}
+
+		for _, lessImport := range imports { // For each import
+			if len(lessImport.Resolved) == 0 && !lessImport.Optional { // Does not resolve
+				errors = append(errors, fmt.Sprintf("%s:%d: @import %q does not resolve relative to the file or the include paths.", GetDiagnosticPath(file, workdir), lessImport.Line, lessImport.Path))
+			}
+		}
+	})
+
+	return errors
+}
+
+// GetUnhashedReferences will return the files of other projects which refer to the unhashed name of this project's CSS
+func (p *LessPlugin) GetUnhashedReferences(n *NoodlesProject) []string {
+	destination := n.GetDefaultLessDestination()
+	unhashedRegex := regexp.MustCompile(`(^|[^\w.-])` + regexp.QuoteMeta(filepath.Base(destination)) + `\b`)
+	references := []string{}
+
+	for name, project := range noodles.Projects { // For each project
+		if name == n.Name || project.SourceDir == "" || filepath.Clean(project.SourceDir) == "." { // Ourselves, or a project that'd have us search the entire workspace
+			continue
+		}
+
+		filepath.Walk(filepath.Join(workdir, project.SourceDir), func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() { // Not a file
+				return nil
+			}
+
+			if content, readErr := ioutil.ReadFile(path); readErr == nil && unhashedRegex.Match(content) { // Refers to our unhashed CSS
+				references = append(references, GetDiagnosticPath(path, workdir)+" ("+name+")")
+			}
+
+			return nil
+		})
 	}
 
-	lessFlags := LessCompilerFlags
-	lessFlags = append(lessFlags, "--lint", n.Source) // Add our source and lint flag
+	sort.Strings(references)
+	return references
+}
 
-	commandOutput := coreutils.ExecCommand("lessc", lessFlags, false) // Call execCommand and get its commandOutput
-	trunk.LogInfo(commandOutput)
+// GetCompilerFlags will return the flags we pass to lessc for this project, being our plugin's flags followed by those of the project
+func (p *LessPlugin) GetCompilerFlags(n *NoodlesProject) []string {
+	flags := append([]string{}, p.CompilerFlags...) // Copy so we never append to our plugin's flags
+	return append(flags, n.Flags...)
+}
 
-	return nil
+// Lint will lint our LESS with lessc, as well as stylelint if the project has a config for it. Warnings have a confidence of 0.5 and errors 1
+func (p *LessPlugin) Lint(n *NoodlesProject, confidence float64) (lintErr error) {
+	n.Source = n.GetDefaultLessSource()
+	sources := n.GetLessSources()
+
+	if lintErr = ValidateFlags("Flags", n.Flags, LessReservedFlags); lintErr != nil { // Flags conflicting with our own
+		return
+	}
+
+	if len(sources) == 0 { // Nothing to lint
+		lintErr = errors.New("Source " + n.Source + " does not match any files")
+		return
+	}
+
+	var diagnostics []Diagnostic
+
+	for _, source := range sources { // For each entry point, since lessc lints one at a time
+		lessFlags := p.GetCompilerFlags(n)
+		lessFlags = append(lessFlags, "--lint", source) // Add our source and lint flag
+
+		commandOutput := coreutils.ExecCommand("lessc", lessFlags, true) // Call execCommand and get its commandOutput
+		lessDiagnostics := ParseLessDiagnostics(commandOutput, workdir)
+
+		if len(lessDiagnostics) == 0 && strings.TrimSpace(commandOutput) != "" { // Output we couldn't parse, so don't hide it
+			trunk.LogWarn(strings.TrimSpace(commandOutput))
+		}
+
+		diagnostics = append(diagnostics, lessDiagnostics...)
+	}
+
+	if configPath := FindStylelintConfig(n.SourceDir); configPath != "" { // Project uses stylelint
+		if !coreutils.ExecutableExists(DependenciesMap["stylelint"].Binary) { // If stylelint does not exist
+			lintErr = errors.New(DependenciesMap["stylelint"].Binary + " is not installed on your system. Please run noodles setup.")
+			return
+		}
+
+		files := []string{}
+
+		WalkLessImports(sources, n.GetLessIncludePaths(), func(file string, imports []LessImport, readErr error) {
+			if strings.HasPrefix(file, workdir) { // Only lint our own stylesheets, not those imported from elsewhere
+				files = append(files, file)
+			}
+		})
+
+		var results []StylelintFileResult
+		if results, lintErr = RunStylelint(configPath, files, lintFix); lintErr != nil { // Failed to run stylelint
+			return
+		}
+
+		diagnostics = append(diagnostics, StylelintDiagnostics(results)...)
+	}
+
+	var errorCount int
+	var shown []Diagnostic
+
+	for _, diagnostic := range diagnostics { // For each problem
+		diagnosticConfidence := 0.5 // Warnings
+
+		if diagnostic.Severity == "error" { // Errors always fail the lint
+			diagnosticConfidence = 1
+			errorCount++
+		}
+
+		if diagnosticConfidence >= confidence { // If the severity is equal to or greater than our requested minimum confidence
+			shown = append(shown, diagnostic)
+		}
+	}
+
+	PrintDiagnostics(shown)
+
+	if errorCount != 0 { // Stylesheets have errors
+		lintErr = fmt.Errorf("found %d error(s) in %s", errorCount, n.Name)
+	}
+
+	return
 }
 
 // PreRun will check if the necessary lessc executable is installed
 func (p *LessPlugin) PreRun(n *NoodlesProject) (preRunErr error) {
 	if !coreutils.ExecutableExists("lessc") { // If the lessc executable does not exist
 		preRunErr = errors.New("lessc is not installed on your system. Please run noodles setup")
+	} else if n.Autoprefix && !coreutils.ExecutableExists(DependenciesMap["autoprefix"].Binary) { // If we need postcss and it does not exist
+		preRunErr = errors.New(DependenciesMap["autoprefix"].Binary + " is not installed on your system. Please run noodles setup")
 	}
 
 	return
 }
 
-// PostRun will handle hash appending for generated CSS files, should it be enabled.
+// PostRun will handle vendor prefixing and hash appending for generated CSS files, should they be enabled.
 func (p *LessPlugin) PostRun(n *NoodlesProject) (postRunErr error) {
+	if n.Autoprefix { // Prefix before hashing, so the hash reflects our final CSS
+		if postRunErr = p.Autoprefix(n); postRunErr != nil {
+			return
+		}
+	}
+
+	output := n.Destination
+
 	if n.AppendHash { // If we should append the hash
 		var fileContent []byte
 		fileContent, postRunErr = ioutil.ReadFile(n.Destination)
@@ -70,7 +238,14 @@ func (p *LessPlugin) PostRun(n *NoodlesProject) (postRunErr error) {
 			RemoveHashedFiles(destDir, "css", fileNameWithoutExtension) // Remove existing hashed files
 
 			newFileName := filepath.Join(destDir, fileNameWithoutExtension+"-"+hash+".css")
-			os.Rename(n.Destination, newFileName)
+			postRunErr = RenameWithSourceMap(n.Destination, newFileName) // Rename the file and its source map
+			output = newFileName
+		}
+	}
+
+	if postRunErr == nil { // Built, so remember what from for incremental builds
+		if saveErr := p.SaveLessBuildState(n, output); saveErr != nil {
+			trunk.LogWarn("Failed to save the build state, so the next incremental build will not be skipped: " + saveErr.Error())
 		}
 	}
 
@@ -91,21 +266,26 @@ func (p *LessPlugin) RequiresPostRun(n *NoodlesProject) error {
 func (p *LessPlugin) Run(n *NoodlesProject) error {
 	var runErr error
 
-	if n.Destination == "" { // If no Destination is set
-		n.Destination = filepath.Join("build", n.SimpleName+".css")
+	if runErr = ValidateFlags("Flags", n.Flags, LessReservedFlags); runErr != nil { // Flags conflicting with our own
+		return runErr
 	}
 
-	if n.Source == "" { // If no Source is set
-		n.Source = filepath.Join("src/less/", n.SimpleName+".less")
+	n.Destination = n.GetDefaultLessDestination()
+	n.Source = n.GetDefaultLessSource()
+	pendingLessBuildStates[n.Name] = p.GetLessBuildState(n) // What we're building from, so changes made while compiling trigger another build
+
+	lessFlags := p.GetCompilerFlags(n)
+
+	if n.SourceMaps { // If we should produce a source map, written to the Destination with .map appended
+		lessFlags = append(lessFlags, "--source-map")
 	}
 
-	lessFlags := LessCompilerFlags
 	lessFlags = append(lessFlags, n.Source, n.Destination) // Add our source and destination to flags
 
-	commandOutput := coreutils.ExecCommand("lessc", lessFlags, false) // Call execCommand and get its commandOutput
+	commandOutput := coreutils.ExecCommand("lessc", lessFlags, true) // Call execCommand and get its commandOutput
 
-	if strings.Contains(commandOutput, "SyntaxError") { // If lessc reported syntax errors
-		runErr = errors.New(commandOutput)
+	if diagnostics := ParseLessDiagnostics(commandOutput, workdir); len(diagnostics) != 0 || strings.Contains(commandOutput, "SyntaxError") { // If lessc reported errors
+		runErr = NewDiagnosticsError(diagnostics, commandOutput)
 	}
 
 	return runErr
@@ -2,12 +2,13 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"github.com/JoshStrobl/trunk"
 	"github.com/stroblindustries/coreutils"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 )
 
@@ -27,7 +28,7 @@ var StrictTypescriptCompilerOptions []string
 // ValidTypeScriptModes is a list of valid TypeScript flag modes
 var ValidTypeScriptModes []string
 
-// ValidTypeScriptTargets is a list of valid TypeScript targets
+// ValidTypeScriptTargets is a list of all TypeScript targets we know of, oldest to newest. See GetValidTargets for those supported by the installed tsc.
 var ValidTypeScriptTargets []string
 
 // Do some Typescript compiler option initing
@@ -47,12 +48,14 @@ func init() {
 
 	StrictTypescriptCompilerOptions = []string{
 		"--forceConsistentCasingInFileNames", // Enforce consistency in file names
+		"--noImplicitOverride",               // Require override on overridden members
+		"--strict",                           // Enable all strict type checking
 	}
 
 	StrictTypescriptCompilerOptions = append(StrictTypescriptCompilerOptions, AdvancedTypescriptCompilerOptions...)
 
 	ValidTypeScriptModes = []string{"simple", "advanced", "strict"}
-	ValidTypeScriptTargets = []string{"ES2017", "ES2018", "ES2019", "ES2020", "ESNext"}
+	ValidTypeScriptTargets = []string{"ES5", "ES2015", "ES2016", "ES2017", "ES2018", "ES2019", "ES2020", "ES2021", "ES2022", "ES2023", "ES2024", "ESNext"}
 }
 
 // Check will check the specified project's settings related to our plugin
@@ -62,36 +65,206 @@ func (p *TypeScriptPlugin) Check(n *NoodlesProject) NoodlesCheckResult {
 	deprecations := []string{}
 	errors := []string{}
 	recommendations := []string{}
+	warnings := []string{}
 
 	if !n.Compress { // Compression not enabled
 		recommendations = append(recommendations, "Compression is not enabled, meaning we will only generate a non-minified JS file. Recommended enabling Compress.")
 	}
 
+	if version, versionErr := p.GetVersion(); versionErr == nil { // Validating against the installed tsc
+		trunk.LogInfo("Validating against tsc " + version.String())
+	} else {
+		warnings = append(warnings, "Unable to query tsc, so Target is validated against all known targets rather than those of the installed tsc: "+versionErr.Error())
+	}
+
 	if n.Mode == "" {
 		recommendations = append(recommendations, "No mode is set, meaning we'll default to Advanced flag set. Recommend setting a Mode.")
-	} else if !ListContains(ValidTypeScriptModes, n.Mode) {
+	} else if !ListContainsExact(ValidTypeScriptModes, strings.ToLower(n.Mode)) {
 		errors = append(errors, "No valid Mode set. Must be simple, advanced, or strict.")
 	}
 
 	if n.Target == "" {
-		recommendations = append(recommendations, "No Target set, meaning we default to the latest formal specification, currently ES2018.")
-	} else if !ListContains(ValidTypeScriptTargets, n.Target) {
-		errors = append(errors, "No valid target set. Must be "+strings.Join(ValidTypeScriptTargets, ", "))
+		recommendations = append(recommendations, "No Target set, meaning we default to the latest formal specification, currently "+p.GetDefaultTarget()+".")
+	} else if _, targetErr := p.NormalizeTarget(n.Target); targetErr != nil {
+		errors = append(errors, targetErr.Error())
+	}
+
+	if n.IsTypeScriptModule() { // Compiling entry points to ES modules
+		for _, entryPoint := range n.EntryPoints { // For each entry point
+			if _, statErr := os.Stat(filepath.Join(workdir, entryPoint)); statErr != nil { // Entry point does not exist
+				errors = append(errors, "Entry point "+entryPoint+" does not exist.")
+			}
+		}
+
+		if strings.HasSuffix(n.Destination, ".js") { // Destination is a file
+			errors = append(errors, "EntryPoints are set, so Destination must be an output directory rather than a .js file.")
+		}
+
+		if n.Source != "" { // Source is unused
+			recommendations = append(recommendations, "EntryPoints are set, so Source is not used. Recommend removing Source.")
+		}
+	}
+
+	errors = append(errors, GetConflictingFlagsErrors("Flags", n.Flags, TypeScriptReservedFlags)...)
+	errors = append(errors, GetConflictingFlagsErrors("MinifierFlags", n.MinifierFlags, TerserReservedFlags)...)
+
+	if len(n.MinifierFlags) != 0 && !n.Compress { // Nothing to minify
+		recommendations = append(recommendations, "MinifierFlags are set but Compress is not enabled, so they are not used. Recommend enabling Compress.")
+	}
+
+	if n.TSConfig != "" { // Project provides its own tsconfig.json
+		if len(n.Flags) != 0 { // tsc --build only reads compiler options from the tsconfig.json
+			warnings = append(warnings, "Flags are not used since TSConfig is set. Recommend moving them into the compilerOptions of "+n.TSConfig+".")
+		}
+
+		if config, readErr := ReadTSConfig(n.GetTSConfigPath()); readErr == nil { // Read the tsconfig.json
+			references := []string{}
+
+			for _, reference := range config.References { // For each project reference
+				references = append(references, filepath.Clean(filepath.Join(filepath.Dir(n.GetTSConfigPath()), reference.Path)))
+			}
+
+			for _, required := range n.GetTypeScriptRequires() { // For each TypeScript project we require
+				requiredProject := noodles.Projects[required]
+				requiredConfigPath := requiredProject.GetTSConfigPath()
+
+				if !ListContainsExact(references, requiredConfigPath) && !ListContainsExact(references, filepath.Dir(requiredConfigPath)) { // Not referenced
+					recommendations = append(recommendations, "Requires "+required+" but "+n.TSConfig+" does not reference it. Recommend adding a reference to "+requiredConfigPath+".")
+				}
+			}
+
+			if sourceMap, _ := config.CompilerOptions["sourceMap"].(bool); n.SourceMaps && !sourceMap { // Source maps enabled but not emitted by tsc
+				recommendations = append(recommendations, "SourceMaps is enabled but "+n.TSConfig+" does not enable sourceMap. Recommend enabling sourceMap so minified source maps point to the TypeScript.")
+			}
+
+			outputOption := "outFile"
+
+			if n.IsTypeScriptModule() { // Modules are emitted to a directory
+				outputOption = "outDir"
+			}
+
+			if output, isString := config.CompilerOptions[outputOption].(string); isString && n.Destination != "" { // Has an outFile or outDir
+				outputPath := filepath.Join(filepath.Dir(n.GetTSConfigPath()), output)
+
+				if outputPath != filepath.Join(workdir, n.Destination) { // Destination differs from the output
+					errors = append(errors, "Destination does not match the "+outputOption+" in "+n.TSConfig+". Destination is used for compression and hashing, so these must match.")
+				}
+			}
+		} else {
+			errors = append(errors, "Failed to read TSConfig: "+readErr.Error())
+		}
 	}
 
 	results["Deprecations"] = deprecations
 	results["Errors"] = errors
 	results["Recommendations"] = recommendations
+	results["Warnings"] = warnings
 
 	return results
 }
 
-// Lint is currently a stub func, offers no functionality yet.
+// Lint will lint our TypeScript with ESLint. Lint takes a Noodles Project and the minimum acceptable confidence, where warnings are 0.5 and errors are 1
 func (p *TypeScriptPlugin) Lint(n *NoodlesProject, confidence float64) (lintErr error) {
-	trunk.LogErr("Linting of TypeScript projects not currently supported.")
+	if !coreutils.ExecutableExists(DependenciesMap["eslint"].Binary) { // If eslint does not exist
+		lintErr = errors.New(DependenciesMap["eslint"].Binary + " is not installed on your system. Please run noodles setup.")
+		return
+	}
+
+	p.SetDefaults(n)
+	sourceDirs := n.GetTypeScriptSourceDirs()
+	n.SourceDir = sourceDirs[0] // Ensure SourceDir reflects any default Source, since our ESLint config is found from it
+
+	lintFiles := []string{}
+
+	for _, dir := range sourceDirs { // For the directory of our Source, or of each entry point
+		var files []string
+		if files, lintErr = coreutils.GetFilesContainsRecursive(dir, ".ts"); lintErr != nil { // Failed to get our TypeScript files
+			lintErr = errors.New("failed to get files: " + lintErr.Error())
+			return
+		}
+
+		for _, file := range files { // For each file
+			if strings.Contains("/"+filepath.ToSlash(file)+"/", "/node_modules/") || ListContainsExact(lintFiles, file) { // A dependency, or in the directory of another entry point
+				continue
+			}
+
+			if !strings.HasSuffix(file, ".d.ts") && (strings.HasSuffix(file, ".ts") || strings.HasSuffix(file, ".tsx")) { // TypeScript source and not a declaration
+				lintFiles = append(lintFiles, file)
+			}
+		}
+	}
+
+	if len(lintFiles) == 0 { // Nothing to lint
+		return
+	}
+
+	var results []ESLintFileResult
+	if results, lintErr = RunESLint(n, lintFiles, lintFix); lintErr != nil { // Failed to run ESLint
+		return
+	}
+
+	var diagnostics []Diagnostic
+	var errorCount int
+
+	for _, result := range results { // For each linted file
+		for _, message := range result.Messages { // For each problem
+			if ESLintSeverityConfidence(message) == 1 { // Errors always fail the lint
+				errorCount++
+			}
+
+			if ESLintSeverityConfidence(message) >= confidence { // If the severity is equal to or greater than our requested minimum confidence
+				severity := "warning"
+
+				if ESLintSeverityConfidence(message) == 1 { // Error or fatal
+					severity = "error"
+				}
+
+				diagnostics = append(diagnostics, Diagnostic{
+					Code:     message.RuleID,
+					Column:   message.Column,
+					File:     GetDiagnosticPath(result.FilePath, workdir),
+					Line:     message.Line,
+					Message:  message.Message,
+					Severity: severity,
+				})
+			}
+		}
+	}
+
+	PrintDiagnostics(diagnostics)
+
+	if errorCount != 0 { // Sources have errors
+		lintErr = fmt.Errorf("found %d error(s) in %s", errorCount, n.Name)
+	}
+
 	return
 }
 
+// GetModeFlags will return the compiler flags for the provided Mode which are supported by the installed tsc
+func (p *TypeScriptPlugin) GetModeFlags(mode string) []string {
+	var modeTypeArgs []string // The mode args we'll be using during compilation
+
+	switch mode {
+	case "simple":
+		modeTypeArgs = SimpleTypescriptCompilerOptions
+	case "advanced":
+		modeTypeArgs = AdvancedTypescriptCompilerOptions
+	case "strict":
+		modeTypeArgs = StrictTypescriptCompilerOptions
+	}
+
+	version, versionErr := p.GetVersion()
+	supportedArgs := []string{}
+
+	for _, arg := range modeTypeArgs { // For each flag
+		if versionErr != nil || version.AtLeast(TypeScriptFlagVersions[arg]) { // Supported by tsc, or unable to tell
+			supportedArgs = append(supportedArgs, arg)
+		}
+	}
+
+	return supportedArgs
+}
+
 // PreRun will check if the necessary executables for TypeScript and compression are installed
 func (p *TypeScriptPlugin) PreRun(n *NoodlesProject) (preRunErr error) {
 	executables := []string{DependenciesMap["compress"].Binary, DependenciesMap["typescript"].Binary}
@@ -99,8 +272,49 @@ func (p *TypeScriptPlugin) PreRun(n *NoodlesProject) (preRunErr error) {
 	for _, executable := range executables { // For each executable
 		if !coreutils.ExecutableExists(executable) { // If this executable does not exist
 			preRunErr = errors.New(executable + " is not installed on your system. Please run noodles setup.")
-			break
+			return
+		}
+	}
+
+	p.SetDefaults(n)
+	preRunErr = p.Validate(n) // Ensure our Mode and Target are supported by the installed tsc
+
+	return
+}
+
+// Minify will minify the provided JavaScript file with terser into a .min.js next to it, chaining any source map, and return the path to the minified file
+func (p *TypeScriptPlugin) Minify(n *NoodlesProject, file string) (minifiedFile string, minifyErr error) {
+	minifiedFile = strings.TrimSuffix(file, ".js") + ".min.js"
+
+	if minifyErr = ValidateFlags("MinifierFlags", n.MinifierFlags, TerserReservedFlags); minifyErr != nil { // Flags conflicting with our own
+		return
+	}
+
+	uglifyArgs := []string{ // Define uglifyArgs
+		file,         // Input
+		"--compress", // Yes, I like to compress things
+		"--mangle",   // Mangle variable names
+	}
+
+	uglifyArgs = append(uglifyArgs, n.MinifierFlags...)       // Pass through our project's minifier flags
+	uglifyArgs = append(uglifyArgs, "--output", minifiedFile) // Write to the .min.js
+
+	if n.IsTypeScriptModule() { // ES modules, so imports and exports must be preserved
+		uglifyArgs = append(uglifyArgs, "--module")
+	}
+
+	if n.SourceMaps { // If we should produce source maps
+		sourceMapOptions := "url='" + filepath.Base(GetSourceMapPath(minifiedFile)) + "'"
+
+		if _, statErr := os.Stat(GetSourceMapPath(file)); statErr == nil { // Chain the source map from tsc, so our minified map points to the TypeScript
+			sourceMapOptions = "content='" + GetSourceMapPath(file) + "'," + sourceMapOptions
 		}
+
+		uglifyArgs = append(uglifyArgs, "--source-map", sourceMapOptions)
+	}
+
+	if closureOutput, terserErr := exec.Command("terser", uglifyArgs...).CombinedOutput(); terserErr != nil { // Run our JavaScript compressor / minifier
+		minifyErr = errors.New("failed to minify " + file + ": " + strings.TrimSpace(string(closureOutput)))
 	}
 
 	return
@@ -108,6 +322,10 @@ func (p *TypeScriptPlugin) PreRun(n *NoodlesProject) (preRunErr error) {
 
 // PostRun will perform compression if the project has enabled it
 func (p *TypeScriptPlugin) PostRun(n *NoodlesProject) (postRunErr error) {
+	if n.IsTypeScriptModule() { // Multiple emitted files
+		return p.PostRunModules(n)
+	}
+
 	destDir := filepath.Dir(n.Destination)
 	fileName := filepath.Base(n.Destination)
 	fileNameWithoutExtension := strings.Replace(fileName, filepath.Ext(n.Destination), "", -1) // Get the base name and remove the extension
@@ -122,40 +340,22 @@ func (p *TypeScriptPlugin) PostRun(n *NoodlesProject) (postRunErr error) {
 		RemoveHashedFiles(destDir, jsExtension, fileNameWithoutExtension) // Remove existing hashed files
 	}
 
+	hashableFile := n.Destination // The file which will have the hash appended
+
 	if n.Compress { // If we should minify the content
 		trunk.LogInfo("Minifying compiled JavaScript.")
 
-		uglifyArgs := []string{ // Define uglifyArgs
-			n.Destination, // Input
-			"--compress",  // Yes, I like to compress things
-			"--mangle",    // Mangle variable names
+		if hashableFile, postRunErr = p.Minify(n, n.Destination); postRunErr != nil { // Failed to minify
+			return
 		}
+	}
 
-		closureOutput := coreutils.ExecCommand("terser", uglifyArgs, true) // Run our JavaScript compressor / minifier and store the output in closureOutput
-		nodeDeprecationRemover, _ := regexp.Compile(`\(node\:.+\n`)        // Remove any lines starting with (node:
-		closureOutput = nodeDeprecationRemover.ReplaceAllString(closureOutput, "")
-		closureOutput = strings.TrimSpace(closureOutput) // Fix trailing newlines
-
-		var minifiedJSDestination string
+	if n.AppendHash { // If we're appending the hash to the .js or .min.js file
+		var fileContent []byte
+		fileContent, postRunErr = ioutil.ReadFile(hashableFile)
 
-		if n.AppendHash { // If we should append the hash, just immediately set our minifiedJSDestination so we can skip our move step
-			hash := CreateHash([]byte(closureOutput))
-			minifiedJSDestination = filepath.Join(destDir, fileNameWithoutExtension+"-"+hash+".min.js")
-		} else {
-			minifiedJSDestination = filepath.Join(destDir, fileNameWithoutExtension+".min.js")
-		}
-
-		postRunErr = coreutils.WriteOrUpdateFile(minifiedJSDestination, []byte(closureOutput), coreutils.NonGlobalFileMode) // Write or update the minified JS file content to build/lowercaseProjectName.min.js
-	} else { // If we're not minifying the content
-		if n.AppendHash { // If we're appending the hash to the .js file
-			var fileContent []byte
-			fileContent, postRunErr = ioutil.ReadFile(n.Destination)
-
-			if postRunErr == nil { // No error during read
-				hash := CreateHash(fileContent)
-				newFileName := filepath.Join(destDir, fileNameWithoutExtension+"-"+hash+".js")
-				os.Rename(n.Destination, newFileName) // Rename the file
-			}
+		if postRunErr == nil { // No error during read
+			postRunErr = RenameWithSourceMap(hashableFile, GetHashedJSFileName(hashableFile, CreateHash(fileContent))) // Rename the file and its source map
 		}
 	}
 
@@ -172,47 +372,52 @@ func (p *TypeScriptPlugin) RequiresPostRun(n *NoodlesProject) error {
 	return nil
 }
 
-// Run will run our TypeScript compilation
-func (p *TypeScriptPlugin) Run(n *NoodlesProject) (runErr error) {
-	if n.Destination == "" { // If no custom Destination is set
+// SetDefaults will set the default Destination, Mode, Source and Target of the project if they are not set
+// Invalid values are left as they are, so Validate can report them
+func (p *TypeScriptPlugin) SetDefaults(n *NoodlesProject) {
+	if n.Destination == "" && n.IsTypeScriptModule() { // If no custom Destination is set for our output directory
+		n.Destination = filepath.Join("build", n.SimpleName)
+	} else if n.Destination == "" { // If no custom Destination is set
 		n.Destination = filepath.Join("build", n.SimpleName+".js")
 	}
 
 	n.Mode = strings.ToLower(n.Mode) // Lowercase n.Mode
 
-	if n.Mode == "" || ((n.Mode != "simple") && (n.Mode != "advanced") && (n.Mode != "strict")) { // If no Mode is set, or is not set to a valid one
+	if n.Mode == "" { // If no Mode is set
 		n.Mode = "advanced" // Pick a reasonable middleground
 	}
 
-	if n.Source == "" { // If no source is defined
+	if n.Source == "" && !n.IsTypeScriptModule() { // If no source is defined
 		n.Source = filepath.Join("src", "typescript", n.SimpleName+".ts")
 	}
 
-	if !ListContains(ValidTypeScriptTargets, n.Target) { // If this is not a valid target
-		n.Target = "ES2019" // Set to 2019
+	if n.Target == "" { // If no target is set
+		n.Target = p.GetDefaultTarget() // Set to the latest formal specification
 	}
+}
 
-	var modeTypeArgs []string // The mode args we'll be using during compilation
+// Run will run our TypeScript compilation through tsc --build, so it is incremental and builds any referenced projects
+func (p *TypeScriptPlugin) Run(n *NoodlesProject) (runErr error) {
+	p.SetDefaults(n)
 
-	switch n.Mode {
-	case "simple":
-		modeTypeArgs = SimpleTypescriptCompilerOptions
-	case "advanced":
-		modeTypeArgs = AdvancedTypescriptCompilerOptions
-	case "strict":
-		modeTypeArgs = StrictTypescriptCompilerOptions
+	if runErr = ValidateFlags("Flags", n.Flags, TypeScriptReservedFlags); runErr != nil { // Flags conflicting with our own
+		return
 	}
 
-	tscFlags := append(modeTypeArgs, []string{ // Set tscFlags to the flags we'll pass to the Typescript copmiler
-		"--target", n.Target, // Add our target
-		"--outFile", n.Destination, // Add our destination
-		n.Source, // Add source
-	}...)
+	if runErr = p.GenerateTSConfig(n, make(map[string]bool)); runErr != nil { // Failed to generate our tsconfig.json files
+		runErr = errors.New("failed to generate tsconfig.json: " + runErr.Error())
+		return
+	}
+
+	tscFlags := []string{
+		"--build", n.GetTSConfigPath(), // Build using the project's tsconfig.json
+		"--pretty", "false", // Plain output we can parse into diagnostics
+	}
 
 	commandOutput := coreutils.ExecCommand("tsc", tscFlags, true) // Call execCommand and get its commandOutput
 
-	if strings.Contains(commandOutput, "error TS") { // If tsc reported errors
-		runErr = errors.New(commandOutput)
+	if strings.Contains(commandOutput, "error TS") { // If tsc reported errors, which are relative to our workdir
+		runErr = NewDiagnosticsError(ParseTypeScriptDiagnostics(commandOutput, workdir), commandOutput)
 	}
 
 	return
@@ -0,0 +1,46 @@
+package main
+
+// This file contains functionality pertaining to post-processing the CSS of LESS projects with PostCSS and autoprefixer
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// GetBrowsers will return the browserslist queries for this project, falling back to those of the workspace
+func (n *NoodlesProject) GetBrowsers() []string {
+	if len(n.Browsers) != 0 { // Project has its own browsers
+		return n.Browsers
+	}
+
+	return noodles.Browsers
+}
+
+// Autoprefix will add the vendor prefixes needed by our browsers to the project's CSS, in place
+func (p *LessPlugin) Autoprefix(n *NoodlesProject) (prefixErr error) {
+	postcssArgs := []string{
+		n.Destination,
+		"--replace",             // Overwrite our CSS rather than writing elsewhere
+		"--use", "autoprefixer", // Add vendor prefixes
+	}
+
+	if n.SourceMaps { // Update the source map lessc produced, which postcss picks up from the sourceMappingURL
+		postcssArgs = append(postcssArgs, "--map")
+	} else {
+		postcssArgs = append(postcssArgs, "--no-map") // postcss inlines source maps by default
+	}
+
+	postcss := exec.Command(DependenciesMap["autoprefix"].Binary, postcssArgs...)
+	postcss.Env = GetNpmGlobalEnv() // autoprefixer is installed globally by setup, not alongside the project
+
+	if browsers := n.GetBrowsers(); len(browsers) != 0 { // Have browsers, otherwise autoprefixer uses any .browserslistrc or its defaults
+		postcss.Env = append(postcss.Env, "BROWSERSLIST="+strings.Join(browsers, ", "))
+	}
+
+	if output, execErr := postcss.CombinedOutput(); execErr != nil { // Failed to post-process our CSS
+		prefixErr = errors.New("failed to autoprefix " + n.Destination + ": " + strings.TrimSpace(string(output)))
+	}
+
+	return
+}
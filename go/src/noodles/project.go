@@ -35,3 +35,30 @@ func (n *NoodlesProject) GetFiles() []string {
 
 	return files
 }
+
+// GetDestination will return the absolute path the project is compiled to, including the Destination we default to when it is not set
+func (n *NoodlesProject) GetDestination() string {
+	destination := n.Destination
+
+	if n.Plugin == "go" {
+		if destination == "" { // If a destination is not set
+			if n.Type == "plugin" { // Plugin
+				destination = filepath.Join("build", n.SimpleName+".so") // build/name.so
+			} else if n.Type != "package" { // Binary, which is what we build when no Type is set
+				destination = filepath.Join("build", n.SimpleName) // build/name (as binary)
+			} // Packages are compiled in our workdir
+		} else if (n.Type == "plugin") && (filepath.Ext(destination) != ".so") { // Destination does not have .so
+			destination = destination + ".so"
+		}
+	} else if n.Plugin == "less" {
+		destination = n.GetDefaultLessDestination()
+	} else if n.Plugin == "typescript" && destination == "" { // The Destination SetDefaults uses
+		if n.IsTypeScriptModule() { // Output directory of modules
+			destination = filepath.Join("build", n.SimpleName)
+		} else {
+			destination = filepath.Join("build", n.SimpleName+".js")
+		}
+	}
+
+	return filepath.Join(workdir, destination) // Combine workdir and destination
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/JoshStrobl/trunk"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var publishCmd = &cobra.Command{
+	Use:               "publish [target...]",
+	Short:             "Publish packed artifacts to configured targets",
+	Long:              "Upload the archives, packages, image tarballs, signatures, SHA256SUMS and release manifest pack created to every, or the provided, Distribution Publish targets",
+	Run:               publish,
+	DisableAutoGenTag: true,
+}
+
+// headerVariableRegex matches the ${VARIABLE} references to environment variables in the Headers of http targets
+var headerVariableRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ReleaseManifest is the description of a release we publish alongside its artifacts
+type ReleaseManifest struct {
+	Created string                `json:"created"`
+	Files   []ReleaseManifestFile `json:"files"`
+	Name    string                `json:"name"`
+	Version string                `json:"version"`
+}
+
+// ReleaseManifestFile is an artifact of a release
+type ReleaseManifestFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+var publishDryRun bool
+
+func init() {
+	publishCmd.Flags().BoolVarP(&publishDryRun, "dry-run", "n", false, "List where each file would be published, without publishing it")
+}
+
+func publish(cmd *cobra.Command, args []string) {
+	targets := args
+
+	if len(targets) == 0 { // Every target
+		for name := range noodles.Distribution.Publish {
+			targets = append(targets, name)
+		}
+
+		sort.Strings(targets)
+	}
+
+	if len(targets) == 0 {
+		trunk.LogFatal("No Distribution Publish targets are configured.")
+	}
+
+	for _, name := range targets {
+		if _, exists := noodles.Distribution.Publish[name]; !exists {
+			trunk.LogFatal(name + " is not a Distribution Publish target.")
+		}
+	}
+
+	files, filesErr := GetPublishFiles(!publishDryRun)
+
+	if filesErr != nil {
+		trunk.LogErrRaw(filesErr)
+		os.Exit(1)
+	}
+
+	failed := false
+
+	for _, name := range targets { // For each target
+		target := noodles.Distribution.Publish[name]
+
+		for _, file := range files {
+			if publishDryRun {
+				location, locationErr := GetPublishLocation(target, filepath.Base(file))
+
+				if locationErr != nil {
+					trunk.LogErrRaw(locationErr)
+					os.Exit(1)
+				}
+
+				fmt.Printf("%s\t%s\n", name, location)
+				continue
+			}
+
+			if location, publishErr := PublishFile(target, file); publishErr == nil {
+				trunk.LogSuccess("Published " + location)
+			} else {
+				trunk.LogErr(fmt.Sprintf("Failed to publish %s to %s: %s", file, name, publishErr.Error()))
+				failed = true
+				break // Don't publish a partial release, such as SHA256SUMS without its archives
+			}
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// GetReleaseManifestPath will return the path to the release manifest we write alongside our artifacts
+func GetReleaseManifestPath() string {
+	return filepath.Join(GetOutputDir(), CondenseArchiveName(noodles.Name)+"-"+noodles.Version.String()+".manifest.json")
+}
+
+// GetPublishFiles will return every file we publish, being our artifacts and their signatures, followed by our SHA256SUMS and release manifest
+// If writeManifest is set, the release manifest is written, otherwise it is only listed
+func GetPublishFiles(writeManifest bool) (files []string, filesErr error) {
+	var artifacts []string
+	if artifacts, filesErr = GetDistributionArtifacts(noodles.Projects); filesErr != nil {
+		return
+	}
+
+	for _, artifact := range artifacts {
+		if _, statErr := os.Stat(artifact); statErr != nil { // Not packed
+			filesErr = fmt.Errorf("%s does not exist. Run noodles pack first.", artifact)
+			return
+		}
+
+		files = append(files, artifact)
+
+		if _, statErr := os.Stat(artifact + MinisignSignatureExtension); statErr == nil { // Signed
+			files = append(files, artifact+MinisignSignatureExtension)
+		}
+	}
+
+	checksums := filepath.Join(GetOutputDir(), ChecksumsFile)
+
+	if _, statErr := os.Stat(checksums); statErr == nil { // Written by pack
+		files = append(files, checksums)
+	}
+
+	if writeManifest {
+		if filesErr = WriteReleaseManifest(files); filesErr != nil {
+			filesErr = fmt.Errorf("Failed to write the release manifest:\n%s", filesErr.Error())
+			return
+		}
+	}
+
+	files = append(files, GetReleaseManifestPath()) // Last, so it is only published once everything it lists is
+	return
+}
+
+// WriteReleaseManifest will write the release manifest of the provided files
+func WriteReleaseManifest(files []string) (writeErr error) {
+	manifest := ReleaseManifest{
+		Created: GetSourceDateEpoch().Format(time.RFC3339),
+		Files:   []ReleaseManifestFile{},
+		Name:    noodles.Name,
+		Version: noodles.Version.String(),
+	}
+
+	for _, file := range files {
+		var info os.FileInfo
+		if info, writeErr = os.Stat(file); writeErr != nil {
+			return
+		}
+
+		var digest string
+		if digest, writeErr = GetFileSHA256(file); writeErr != nil {
+			return
+		}
+
+		manifest.Files = append(manifest.Files, ReleaseManifestFile{Name: filepath.Base(file), SHA256: digest, Size: info.Size()})
+	}
+
+	var content []byte
+	if content, writeErr = json.MarshalIndent(manifest, "", "\t"); writeErr == nil {
+		writeErr = ioutil.WriteFile(GetReleaseManifestPath(), append(content, '\n'), 0644)
+	}
+
+	return
+}
+
+// ExpandPublishPlaceholders will replace {version} and {workspace} in the provided path or URL
+func ExpandPublishPlaceholders(value string) string {
+	return strings.NewReplacer("{version}", noodles.Version.String(), "{workspace}", CondenseArchiveName(noodles.Name)).Replace(value)
+}
+
+// GetPublishLocation will return where the file with the provided name is published to on the target, being a path or URL
+func GetPublishLocation(target NoodlesPublishTarget, name string) (location string, locationErr error) {
+	switch target.Type {
+	case "dir":
+		location = filepath.Join(ExpandPublishPlaceholders(target.Path), name)
+	case "http":
+		location = strings.TrimSuffix(ExpandPublishPlaceholders(target.URL), "/") + "/" + url.PathEscape(name)
+	case "s3":
+		location = GetS3URL(target, path.Join(strings.Trim(ExpandPublishPlaceholders(target.Path), "/"), name))
+	default:
+		locationErr = errors.New("Unsupported publish type: " + target.Type)
+	}
+
+	return
+}
+
+// PublishFile will publish the file to the target, retrying failures which may be temporary, and return where it was published to
+func PublishFile(target NoodlesPublishTarget, file string) (location string, publishErr error) {
+	attempts := target.Attempts
+
+	if attempts < 1 {
+		attempts = 3
+	}
+
+	if location, publishErr = GetPublishLocation(target, filepath.Base(file)); publishErr != nil {
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		var retryable bool
+
+		switch target.Type {
+		case "dir":
+			retryable, publishErr = PublishToDir(location, file)
+		case "http":
+			retryable, publishErr = PublishToHTTP(target, location, file)
+		case "s3":
+			retryable, publishErr = PublishToS3(target, location, file)
+		default:
+			publishErr = errors.New("Unsupported publish type: " + target.Type)
+		}
+
+		if publishErr == nil || !retryable || attempt >= attempts { // Published, or won't succeed by trying again
+			return
+		}
+
+		delay := time.Duration(1<<uint(attempt-1)) * time.Second // 1s, 2s, 4s and so on
+		trunk.LogWarn(fmt.Sprintf("Failed to publish %s, retrying in %s: %s", filepath.Base(file), delay, publishErr.Error()))
+		time.Sleep(delay)
+	}
+}
+
+// PublishToDir will copy the file to the provided destination, such as on an NFS mount, only replacing any existing file once it is complete
+func PublishToDir(destination, file string) (retryable bool, publishErr error) {
+	partialPath := destination + ".partial"
+
+	if publishErr = CopyFile(file, partialPath); publishErr != nil { // Such as the mount being unavailable
+		os.Remove(partialPath)
+		retryable = true
+		return
+	}
+
+	if publishErr = os.Rename(partialPath, destination); publishErr != nil {
+		os.Remove(partialPath)
+		retryable = true
+	}
+
+	return
+}
+
+// PublishToHTTP will PUT the file to the provided URL with the Headers of the target
+func PublishToHTTP(target NoodlesPublishTarget, location, file string) (retryable bool, publishErr error) {
+	var request *http.Request
+	if request, publishErr = NewPublishRequest(location, file); publishErr != nil {
+		return
+	}
+
+	for name, value := range target.Headers {
+		var expanded string
+		if expanded, publishErr = ExpandHeaderVariables(value); publishErr != nil {
+			publishErr = fmt.Errorf("Failed to set the %s header: %s", name, publishErr.Error())
+			return
+		}
+
+		request.Header.Set(name, expanded)
+	}
+
+	retryable, publishErr = SendPublishRequest(request)
+	return
+}
+
+// ExpandHeaderVariables will replace each ${VARIABLE} in the header value with the environment variable, leaving any other $ as is
+func ExpandHeaderVariables(value string) (expanded string, expandErr error) {
+	expanded = headerVariableRegex.ReplaceAllStringFunc(value, func(reference string) string {
+		name := reference[2 : len(reference)-1] // Strip ${ and }
+		variable, set := os.LookupEnv(name)
+
+		if !set && expandErr == nil { // Rather than sending an empty value, such as an empty Authorization
+			expandErr = errors.New(name + " is not set in our environment")
+		}
+
+		return variable
+	})
+
+	return
+}
+
+// PublishToS3 will PUT the file to the provided URL of the target's bucket, signed with AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+func PublishToS3(target NoodlesPublishTarget, location, file string) (retryable bool, publishErr error) {
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+
+	if accessKey == "" || secretKey == "" {
+		publishErr = errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to publish to s3 targets")
+		return
+	}
+
+	var request *http.Request
+	if request, publishErr = NewPublishRequest(location, file); publishErr != nil {
+		return
+	}
+
+	var digest string
+	if digest, publishErr = GetFileSHA256(file); publishErr != nil {
+		return
+	}
+
+	SignS3Request(request, GetS3Region(target), digest, accessKey, secretKey, os.Getenv("AWS_SESSION_TOKEN"), time.Now())
+	retryable, publishErr = SendPublishRequest(request)
+	return
+}
+
+// NewPublishRequest will return a PUT request of the file to the provided URL
+func NewPublishRequest(location, file string) (request *http.Request, requestErr error) {
+	var content []byte
+	if content, requestErr = ioutil.ReadFile(file); requestErr != nil {
+		return
+	}
+
+	if request, requestErr = http.NewRequest(http.MethodPut, location, bytes.NewReader(content)); requestErr != nil {
+		return
+	}
+
+	contentType := "application/octet-stream"
+
+	if filepath.Ext(file) == ".json" { // Our release manifest
+		contentType = "application/json"
+	}
+
+	request.Header.Set("Content-Type", contentType)
+	return
+}
+
+// SendPublishRequest will send the request, where failures to connect, server errors and rate limiting may succeed if retried
+func SendPublishRequest(request *http.Request) (retryable bool, sendErr error) {
+	client := &http.Client{Timeout: 10 * time.Minute}
+
+	var response *http.Response
+	if response, sendErr = client.Do(request); sendErr != nil { // Such as failing to connect
+		retryable = true
+		return
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 { // Uploaded
+		return
+	}
+
+	body, _ := ioutil.ReadAll(response.Body)
+	message := strings.TrimSpace(string(body))
+
+	if len(message) > 200 { // Such as a full HTML error page
+		message = message[:200] + "..."
+	}
+
+	sendErr = errors.New(response.Status)
+
+	if message != "" {
+		sendErr = errors.New(response.Status + ": " + message)
+	}
+
+	retryable = response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+	return
+}
+
+// GetS3Region will return the region of the s3 target, defaulting to us-east-1
+func GetS3Region(target NoodlesPublishTarget) string {
+	if target.Region != "" {
+		return target.Region
+	}
+
+	return "us-east-1"
+}
+
+// GetS3URL will return the URL of the key within the bucket of the s3 target
+func GetS3URL(target NoodlesPublishTarget, key string) string {
+	endpoint := strings.TrimSuffix(target.Endpoint, "/")
+
+	if endpoint == "" { // AWS itself
+		endpoint = "https://s3." + GetS3Region(target) + ".amazonaws.com"
+	}
+
+	if target.VirtualHosted { // Such as https://bucket.s3.us-east-1.amazonaws.com/key
+		if endpointURL, parseErr := url.Parse(endpoint); parseErr == nil {
+			endpointURL.Host = target.Bucket + "." + endpointURL.Host
+			return endpointURL.String() + S3EscapePath("/"+key)
+		}
+	}
+
+	return endpoint + S3EscapePath("/"+target.Bucket+"/"+key)
+}
+
+// S3EscapePath will escape the path the way AWS Signature Version 4 requires, being every byte other than unreserved characters and /
+func S3EscapePath(unescaped string) string {
+	var escaped strings.Builder
+
+	for _, b := range []byte(unescaped) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || strings.IndexByte("-._~/", b) != -1 {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+
+	return escaped.String()
+}
+
+// SignS3Request will sign the request and every header it has with AWS Signature Version 4, using the hex SHA-256 digest of its payload
+func SignS3Request(request *http.Request, region, payloadDigest, accessKey, secretKey, sessionToken string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := amzDate[:8] + "/" + region + "/s3/aws4_request"
+
+	request.Header.Set("X-Amz-Content-Sha256", payloadDigest)
+	request.Header.Set("X-Amz-Date", amzDate)
+
+	if sessionToken != "" { // Temporary credentials
+		request.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headers := map[string]string{"host": request.URL.Host}
+	names := []string{"host"}
+
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+		names = append(names, strings.ToLower(name))
+	}
+
+	sort.Strings(names)
+	var canonicalHeaders string
+
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{request.Method, request.URL.EscapedPath(), request.URL.RawQuery, canonicalHeaders, signedHeaders, payloadDigest}, "\n")
+	canonicalDigest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalDigest[:])
+
+	key := []byte("AWS4" + secretKey)
+
+	for _, part := range []string{amzDate[:8], region, "s3", "aws4_request", stringToSign} { // Derive our signing key, then sign
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+hex.EncodeToString(key))
+}
@@ -11,7 +11,8 @@ import (
 // This file contains our functionality for our Requires system
 
 // RunRequires will run project pre/postrun function or a script before/after (based on), based on what is provided in requires
-func RunRequires(operationType string, requires []string) {
+// Running stops at the first script which fails, returning its error
+func RunRequires(operationType string, requires []string) (requiresErr error) {
 	if len(requires) > 0 {
 		trunk.LogInfo("Running Requires on " + operationType)
 
@@ -53,9 +54,13 @@ func RunRequires(operationType string, requires []string) {
 			} else if _, exists := noodles.Scripts[projectOrScriptName]; exists { // If this is a script
 				if (operationType == "RequiresPreRun" && !scriptRunAfter) || // Running before
 					(operationType == "RequiresPostRun" && scriptRunAfter) { // Running after and should run after
-					RunScript(projectOrScriptName) // Call RunScript
+					if requiresErr = RunScript(projectOrScriptName, nil, nil); requiresErr != nil { // Call RunScript, with only its own Arguments
+						return
+					}
 				}
 			}
 		}
 	}
+
+	return
 }
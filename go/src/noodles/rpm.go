@@ -0,0 +1,81 @@
+package main
+
+// This file contains functionality pertaining to creating .rpm packages in-process
+
+import (
+	"fmt"
+	"github.com/google/rpmpack"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// WriteRPM will write a .rpm package of our files, dependencies and maintainer scripts
+func WriteRPM(w io.Writer, info PackageInfo, epoch time.Time) (writeErr error) {
+	metadata := rpmpack.RPMMetaData{
+		Arch:        info.Arch,
+		BuildHost:   "localhost", // Not our hostname, so our package is reproducible
+		BuildTime:   epoch,
+		Compressor:  "gzip",
+		Description: info.Description,
+		Licence:     info.License,
+		Name:        info.Name,
+		Packager:    info.Maintainer,
+		Release:     info.Release,
+		Summary:     strings.TrimSpace(strings.SplitN(info.Description, "\n", 2)[0]), // First line, the same as our .deb
+		URL:         info.Homepage,
+		Version:     info.Version,
+	}
+
+	for _, dependency := range info.Depends {
+		var relation *rpmpack.Relation
+		if relation, writeErr = rpmpack.NewRelation(dependency.Name + dependency.Operator + dependency.Version); writeErr != nil {
+			writeErr = fmt.Errorf("Invalid dependency %s:\n%s", dependency.Name, writeErr.Error())
+			return
+		}
+
+		metadata.Requires = append(metadata.Requires, relation)
+	}
+
+	var rpm *rpmpack.RPM
+	if rpm, writeErr = rpmpack.NewRPM(metadata); writeErr != nil {
+		return
+	}
+
+	for _, file := range info.Files {
+		var content []byte
+		if content, writeErr = ioutil.ReadFile(file.Source); writeErr != nil {
+			return
+		}
+
+		rpmFile := rpmpack.RPMFile{
+			Body:  content,
+			Group: "root",
+			MTime: uint32(file.ModTime.Unix()),
+			Mode:  uint(file.Mode),
+			Name:  file.Path,
+			Owner: "root",
+		}
+
+		if file.Config { // Kept across upgrades, with any changes the user made
+			rpmFile.Type = rpmpack.ConfigFile | rpmpack.NoReplaceFile
+		}
+
+		rpm.AddFile(rpmFile)
+	}
+
+	scripts := map[string]func(string){
+		"PostInstall": rpm.AddPostin,
+		"PostRemove":  rpm.AddPostun,
+		"PreInstall":  rpm.AddPrein,
+		"PreRemove":   rpm.AddPreun,
+	}
+
+	for name, script := range info.Scripts {
+		scripts[name](script)
+	}
+
+	writeErr = rpm.Write(w)
+	return
+}
@@ -3,95 +3,364 @@ package main
 // Script Functionality
 
 import (
+	"errors"
 	"fmt"
 	"github.com/JoshStrobl/trunk"
 	"github.com/spf13/cobra"
 	"github.com/stroblindustries/coreutils"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
 var scriptCmd = &cobra.Command{
-	Use:               "script",
+	Use:               "script [-- args...]",
 	Aliases:           []string{"run-script"},
 	Short:             "Run a custom script",
-	Long:              "Run a custom script",
+	Long:              "Run a custom script, passing any arguments after -- to it in place of {{args}} in its Arguments, or after them. Its Exec, Arguments, Directory, File and Env may reference ${workdir}, ${name}, ${version}, ${env.NAME} and ${projects.NAME.FIELD}, with $${...} for a literal ${...} such as a shell variable",
 	Run:               script,
 	DisableAutoGenTag: true,
 }
 
+// scriptArgsPlaceholder is replaced with the arguments provided after --
+const scriptArgsPlaceholder = "{{args}}"
+
+// scriptParamRegex matches the names our script Params can have
+var scriptParamRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// scriptVariableRegex matches the ${variable} references we interpolate in scripts, as well as those escaped as $${variable}
+var scriptVariableRegex = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
 var verbose bool
 var selectedScript string
+var scriptParams []string
 
 func init() {
 	scriptCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose mode.")
 	scriptCmd.Flags().StringVarP(&selectedScript, "script", "s", "", "Name of the script we're running")
+	scriptCmd.Flags().StringArrayVarP(&scriptParams, "param", "p", []string{}, "Parameter of the script to set, as name=value. Can be provided multiple times")
 }
 
 func script(cmd *cobra.Command, args []string) {
+	exitCode := 0
+
 	if selectedScript == "" { // If no script is set
-		for name := range noodles.Scripts {
-			RunScript(name)
+		if len(args) != 0 || len(scriptParams) != 0 { // Can't know which script these are for
+			trunk.LogFatal("Extra arguments and --param require a script to be selected with --script")
+		}
+
+		for name, script := range noodles.Scripts {
+			if script.Exec == "" { // Nothing to run, which is only an error when the script is selected
+				continue
+			}
+
+			if runErr := RunScript(name, nil, nil); runErr != nil {
+				trunk.LogErrRaw(runErr)
+				exitCode = GetScriptExitCode(runErr)
+			}
 		}
 	} else { // If a script is set
-		RunScript(selectedScript)
+		params, parseErr := ParseScriptParams(scriptParams)
+
+		if parseErr != nil {
+			trunk.LogFatal(parseErr.Error())
+		}
+
+		if runErr := RunScript(selectedScript, args, params); runErr != nil {
+			trunk.LogErrRaw(runErr)
+			exitCode = GetScriptExitCode(runErr)
+		}
+	}
+
+	if exitCode != 0 { // A script failed
+		os.Exit(exitCode)
 	}
 }
 
-// RunScript will run the script provided
-func RunScript(name string) {
-	script, _ := noodles.Scripts[name] // Get our script
+// GetScriptExitCode will get the exit status of the failed script, or 1 if it failed without exiting
+func GetScriptExitCode(runErr error) int {
+	var exitErr *exec.ExitError
 
-	if script.Exec != "" { // If there is an executable
-		trunk.LogInfo("Running script: " + name)
+	if errors.As(runErr, &exitErr) && exitErr.ExitCode() > 0 { // Exited non-zero, rather than being killed
+		return exitErr.ExitCode()
+	}
 
-		RunRequires("RequiresPreRun", script.Requires)
+	return 1
+}
 
-		if script.UseGoEnv { // If we should be enforcing Go env
-			ToggleGoEnv(true)                                                        // Toggle env on
-			script.Directory = filepath.Join(workdir, "go", "src", script.Directory) // Ensure we prepend workdir and go
-		} else {
-			script.Directory = filepath.Join(workdir, script.Directory) // Ensure we prepend the workdir
+// ParseScriptParams will parse the name=value pairs provided with --param
+func ParseScriptParams(pairs []string) (params map[string]string, parseErr error) {
+	params = make(map[string]string)
+
+	for _, pair := range pairs {
+		keyValue := strings.SplitN(pair, "=", 2)
+
+		if len(keyValue) != 2 || keyValue[0] == "" { // Not name=value
+			parseErr = fmt.Errorf("%s is not a parameter in the form of name=value", pair)
+			return
 		}
 
-		if script.Directory != "" { // If we should run this command in a directory
-			failedToChange := os.Chdir(script.Directory) // Change to the directory
+		params[keyValue[0]] = keyValue[1]
+	}
 
-			if failedToChange != nil { // If we failed to change to the directory
-				trunk.LogErrRaw(fmt.Errorf("Failed to change to the following directory: %s\n", script.Directory))
+	return
+}
 
-				if verbose {
-					trunk.LogDebug(fmt.Sprintf("Full error: %s\n", failedToChange))
-				}
+// GetScriptArguments will get the Arguments of the script with its params and extra arguments substituted in
+// Extra arguments replace an argument of only {{args}}, are joined by spaces within other arguments, or are appended if there is no {{args}}
+func GetScriptArguments(name string, script NoodlesScript, extraArgs []string, params map[string]string) (arguments []string, argsErr error) {
+	values := make(map[string]string)
 
-				return // Don't continue with exec
-			}
+	for param, defaultValue := range script.Params {
+		values[param] = defaultValue
+	}
+
+	for param, value := range params {
+		if _, exists := script.Params[param]; !exists { // Not declared on the script
+			argsErr = fmt.Errorf("%s is not a parameter of the script %s", param, name)
+			return
+		}
+
+		values[param] = value
+	}
+
+	arguments = []string{}
+	usedExtraArgs := false
+
+	for _, argument := range script.Arguments {
+		if argument == scriptArgsPlaceholder { // Replaced by each of our extra arguments
+			arguments = append(arguments, extraArgs...)
+			usedExtraArgs = true
+			continue
+		}
+
+		for param, value := range values {
+			argument = strings.Replace(argument, "{{"+param+"}}", value, -1)
+		}
+
+		if strings.Contains(argument, scriptArgsPlaceholder) {
+			argument = strings.Replace(argument, scriptArgsPlaceholder, strings.Join(extraArgs, " "), -1)
+			usedExtraArgs = true
 		}
 
+		arguments = append(arguments, argument)
+	}
+
+	if !usedExtraArgs { // No {{args}}, so append them
+		arguments = append(arguments, extraArgs...)
+	}
+
+	return
+}
+
+// GetScriptVariable will get the value of a variable we interpolate, being workdir, name, version, env.NAME or projects.NAME.FIELD
+func GetScriptVariable(variable string) (value string, variableErr error) {
+	switch {
+	case variable == "workdir":
+		value = workdir
+	case variable == "name":
+		value = noodles.Name
+	case variable == "version":
+		value = noodles.Version.String()
+	case strings.HasPrefix(variable, "env."):
+		envName := strings.TrimPrefix(variable, "env.")
+		var set bool
+
+		if value, set = os.LookupEnv(envName); !set {
+			variableErr = fmt.Errorf("${%s} refers to %s, which is not set in our environment", variable, envName)
+		}
+	case strings.HasPrefix(variable, "projects.") && strings.Count(variable, ".") >= 2:
+		nameAndField := strings.TrimPrefix(variable, "projects.")
+		separator := strings.LastIndex(nameAndField, ".") // Project names may contain a ., fields don't
+		value, variableErr = GetProjectVariable(nameAndField[:separator], nameAndField[separator+1:])
+	default:
+		variableErr = fmt.Errorf("${%s} is not a known variable. Variables are workdir, name, version, env.NAME and projects.NAME.FIELD, and $${...} is a literal ${...}", variable)
+	}
+
+	return
+}
+
+// GetProjectVariable will get the value of the field of the project for interpolation, with paths being absolute
+func GetProjectVariable(name string, field string) (value string, variableErr error) {
+	project, exists := noodles.Projects[name]
+
+	if !exists {
+		variableErr = fmt.Errorf("${projects.%s.%s} refers to %s, which is not a valid project", name, field, name)
+		return
+	}
+
+	switch field {
+	case "Destination":
+		value = project.GetDestination()
+	case "ModuleDir":
+		value = project.GetModuleDir()
+	case "Plugin":
+		value = project.Plugin
+	case "SimpleName":
+		value = project.SimpleName
+	case "Source":
+		value = filepath.Join(workdir, project.Source)
+	case "SourceDir":
+		value = filepath.Join(workdir, project.SourceDir)
+	case "Type":
+		value = project.Type
+	default:
+		variableErr = fmt.Errorf("${projects.%s.%s} is not a known variable. Project fields are Destination, ModuleDir, Plugin, SimpleName, Source, SourceDir and Type", name, field)
+	}
+
+	return
+}
+
+// InterpolateScriptVariables will replace each ${variable} in the value with the value of the variable, and each $${variable} with a literal ${variable}
+func InterpolateScriptVariables(value string) (interpolated string, interpolateErr error) {
+	interpolated = scriptVariableRegex.ReplaceAllStringFunc(value, func(reference string) string {
+		if strings.HasPrefix(reference, "$$") { // Escaped, such as for a shell variable
+			return reference[1:]
+		}
+
+		variableValue, variableErr := GetScriptVariable(reference[2 : len(reference)-1]) // Strip ${ and }
+
+		if variableErr != nil && interpolateErr == nil { // Only report the first unknown variable
+			interpolateErr = variableErr
+		}
+
+		return variableValue
+	})
+
+	return
+}
+
+// InterpolateScript will return a copy of the script with the variables in its Exec, Arguments, Directory, File and Env interpolated
+func InterpolateScript(script NoodlesScript) (interpolated NoodlesScript, interpolateErr error) {
+	interpolated = script
+	interpolated.Arguments = make([]string, len(script.Arguments)) // Don't change the Arguments of our config
+	interpolated.Env = make(map[string]string)
+
+	for _, value := range []*string{&interpolated.Exec, &interpolated.Directory, &interpolated.File} {
+		if *value, interpolateErr = InterpolateScriptVariables(*value); interpolateErr != nil {
+			return
+		}
+	}
+
+	for index, argument := range script.Arguments {
+		if interpolated.Arguments[index], interpolateErr = InterpolateScriptVariables(argument); interpolateErr != nil {
+			return
+		}
+	}
+
+	for key, value := range script.Env {
+		if interpolated.Env[key], interpolateErr = InterpolateScriptVariables(value); interpolateErr != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// RunScript will run the script provided, with any extra arguments and params, after the scripts it requires
+func RunScript(name string, extraArgs []string, params map[string]string) (runErr error) {
+	script, exists := noodles.Scripts[name] // Get our script
+
+	if !exists {
+		runErr = errors.New(name + " is not a valid script")
+		return
+	}
+
+	if script.Exec == "" { // If there is no executable
+		runErr = errors.New("No executable set for the script: " + name)
+		return
+	}
+
+	if script, runErr = InterpolateScript(script); runErr != nil {
+		runErr = fmt.Errorf("%s: %w", name, runErr)
+		return
+	}
+
+	if script.Arguments, runErr = GetScriptArguments(name, script, extraArgs, params); runErr != nil {
+		return
+	}
+
+	trunk.LogInfo("Running script: " + name)
+
+	if runErr = RunRequires("RequiresPreRun", script.Requires); runErr != nil { // A script we require failed
+		runErr = fmt.Errorf("Skipped %s as a script it requires failed: %w", name, runErr)
+		return
+	}
+
+	if script.UseGoEnv { // If we should be enforcing Go env
+		ToggleGoEnv(true) // Toggle env on
+	}
+
+	if !filepath.IsAbs(script.Directory) { // Relative, rather than already absolute such as from ${workdir}
+		if script.UseGoEnv {
+			script.Directory = filepath.Join(workdir, "go", "src", script.Directory) // Ensure we prepend workdir and go
+		} else {
+			script.Directory = filepath.Join(workdir, script.Directory) // Ensure we prepend the workdir
+		}
+	}
+
+	if failedToChange := os.Chdir(script.Directory); failedToChange == nil { // Changed to the directory to run this command in
 		if verbose {
 			commandRunning := script.Exec + " " + (strings.Join(script.Arguments, " "))
 			trunk.LogDebug("Running: " + commandRunning)
 		}
 
-		output := coreutils.ExecCommand(script.Exec, script.Arguments, false)
-		output = CleanupGoCompilerOutput(output)
-
-		if len(output) != 0 { // Have output
-			trunk.LogInfo(output)
+		if execErr := ExecScript(script); execErr != nil {
+			runErr = fmt.Errorf("%s failed: %w", name, execErr)
 		}
+	} else {
+		runErr = fmt.Errorf("Failed to change to the following directory: %s", script.Directory)
 
-		if (script.File != "") && script.Redirect { // If we should redirect output to a file
-			coreutils.WriteOrUpdateFile(script.File, []byte(output), coreutils.NonGlobalFileMode)
+		if verbose {
+			trunk.LogDebug(fmt.Sprintf("Full error: %s\n", failedToChange))
 		}
+	}
+
+	if script.UseGoEnv { // If we should be enforcing Go env
+		ToggleGoEnv(false) // Toggle env off
+	}
 
-		if script.UseGoEnv { // If we should be enforcing Go env
-			ToggleGoEnv(false) // Toggle env off
+	os.Chdir(workdir) // Change back to the work dir if needed
+
+	if runErr != nil { // Don't run what should only run after we succeed
+		return
+	}
+
+	runErr = RunRequires("RequiresPostRun", script.Requires)
+	return
+}
+
+// ExecScript will run the executable of the script, streaming its output as it arrives and teeing it to its File if it should be redirected
+func ExecScript(script NoodlesScript) (execErr error) {
+	runner := exec.Command(script.Exec, script.Arguments...)
+	runner.Stdout = os.Stdout // Our terminal, so the script sees a TTY
+	runner.Stderr = os.Stderr
+
+	runner.Env = os.Environ() // Includes our GOPATH when using the Go env
+
+	for key, value := range script.Env { // Set after our environment, so these take precedence
+		runner.Env = append(runner.Env, key+"="+value)
+	}
+
+	if script.Interactive { // Attach our stdin, so the script can prompt
+		runner.Stdin = os.Stdin
+	}
+
+	if (script.File != "") && script.Redirect { // If we should redirect output to a file
+		var file *os.File
+		if file, execErr = os.OpenFile(script.File, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, coreutils.NonGlobalFileMode); execErr != nil {
+			return
 		}
 
-		os.Chdir(workdir) // Change back to the work dir if needed
-		RunRequires("RequiresPostRun", script.Requires)
-	} else {
-		trunk.LogErr("No executable set for the script: " + name)
+		defer file.Close()
+
+		runner.Stdout = io.MultiWriter(os.Stdout, file)
+		runner.Stderr = io.MultiWriter(os.Stderr, file)
 	}
+
+	execErr = runner.Run()
+	return
 }
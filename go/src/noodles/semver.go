@@ -0,0 +1,186 @@
+package main
+
+// This file contains functionality pertaining to semantic versions, such as the Version of our workspace
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SemanticVersion is a version as described by semver.org, such as 2.0.0-rc.1
+type SemanticVersion struct {
+	Build      string // Build metadata, such as the 5114f85 in 1.0.0+5114f85
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	PreRelease []string // Dot separated identifiers, such as rc and 1 in 1.0.0-rc.1
+}
+
+// NoodlesVersion is the Version of our workspace, which older noodles.toml files write as a number such as 0.1
+type NoodlesVersion struct {
+	SemanticVersion
+	IsNumber bool // Written as a number, so we migrated it
+}
+
+// semanticVersionRegex matches a semantic version, with its optional pre-release and build metadata
+var semanticVersionRegex = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// ParseSemanticVersion will parse the provided version, which may have a leading v
+func ParseSemanticVersion(version string) (semver SemanticVersion, parseErr error) {
+	matches := semanticVersionRegex.FindStringSubmatch(strings.TrimPrefix(strings.TrimSpace(version), "v"))
+
+	if matches == nil {
+		parseErr = errors.New(version + " is not a semantic version, such as 1.2.0 or 2.0.0-rc.1")
+		return
+	}
+
+	semver.Major, _ = strconv.ParseUint(matches[1], 10, 64)
+	semver.Minor, _ = strconv.ParseUint(matches[2], 10, 64)
+	semver.Patch, _ = strconv.ParseUint(matches[3], 10, 64)
+
+	if matches[4] != "" { // Has a pre-release
+		semver.PreRelease = strings.Split(matches[4], ".")
+	}
+
+	semver.Build = matches[5]
+	return
+}
+
+// String will return the version in its semver form
+func (v SemanticVersion) String() string {
+	version := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+
+	if len(v.PreRelease) != 0 {
+		version += "-" + strings.Join(v.PreRelease, ".")
+	}
+
+	if v.Build != "" {
+		version += "+" + v.Build
+	}
+
+	return version
+}
+
+// Bump will return the next major, minor, patch or pre-release version, dropping any build metadata
+// Bumping a pre-release to the release it precedes, such as 2.0.0-rc.1 to 2.0.0, only drops the pre-release
+// Pre-releases are bumped by their last number, such as rc.1 to rc.2, or start at preID.1 of the next patch
+func (v SemanticVersion) Bump(part string, preID string) (bumped SemanticVersion, bumpErr error) {
+	bumped = SemanticVersion{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	isPreRelease := len(v.PreRelease) != 0
+
+	switch part {
+	case "major":
+		if !isPreRelease || v.Minor != 0 || v.Patch != 0 { // Not a pre-release of this major
+			bumped = SemanticVersion{Major: v.Major + 1}
+		}
+	case "minor":
+		if !isPreRelease || v.Patch != 0 { // Not a pre-release of this minor
+			bumped = SemanticVersion{Major: v.Major, Minor: v.Minor + 1}
+		}
+	case "patch":
+		if !isPreRelease { // Not a pre-release of this patch
+			bumped.Patch++
+		}
+	case "pre":
+		if preID == "" || !semanticVersionRegex.MatchString("0.0.0-"+preID) { // Not a valid identifier
+			bumpErr = errors.New(preID + " is not a valid pre-release identifier")
+			return
+		}
+
+		if !isPreRelease { // Start a pre-release of the next patch
+			bumped.Patch++
+			bumped.PreRelease = []string{preID, "1"}
+		} else if v.PreRelease[0] != preID { // Switching identifier, such as from beta to rc
+			bumped.PreRelease = []string{preID, "1"}
+		} else {
+			bumped.PreRelease = append([]string{}, v.PreRelease...)
+			last := len(bumped.PreRelease) - 1
+
+			if number, convErr := strconv.ParseUint(bumped.PreRelease[last], 10, 64); convErr == nil { // Ends with a number
+				bumped.PreRelease[last] = strconv.FormatUint(number+1, 10)
+			} else {
+				bumped.PreRelease = append(bumped.PreRelease, "1")
+			}
+		}
+	default:
+		bumpErr = errors.New(part + " is not one of major, minor, patch or pre")
+	}
+
+	return
+}
+
+// Compare will return -1, 0 or 1 when the version precedes, equals or follows the other, ignoring build metadata as semver.org does
+func (v SemanticVersion) Compare(other SemanticVersion) int {
+	for _, pair := range [][2]uint64{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] != pair[1] {
+			return compareUint(pair[0], pair[1])
+		}
+	}
+
+	if len(v.PreRelease) == 0 || len(other.PreRelease) == 0 { // A release follows its pre-releases
+		return compareUint(uint64(len(other.PreRelease)), uint64(len(v.PreRelease)))
+	}
+
+	for i := 0; i < len(v.PreRelease) && i < len(other.PreRelease); i++ {
+		a, aErr := strconv.ParseUint(v.PreRelease[i], 10, 64)
+		b, bErr := strconv.ParseUint(other.PreRelease[i], 10, 64)
+
+		switch {
+		case aErr == nil && bErr == nil: // Both numeric
+			if a != b {
+				return compareUint(a, b)
+			}
+		case aErr == nil: // Numeric identifiers precede alphanumeric ones
+			return -1
+		case bErr == nil:
+			return 1
+		case v.PreRelease[i] != other.PreRelease[i]:
+			return strings.Compare(v.PreRelease[i], other.PreRelease[i])
+		}
+	}
+
+	return compareUint(uint64(len(v.PreRelease)), uint64(len(other.PreRelease))) // More identifiers follow fewer
+}
+
+// compareUint will return -1, 0 or 1 when a is less than, equal to or greater than b
+func compareUint(a, b uint64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+
+	return 0
+}
+
+// UnmarshalTOML will decode our Version from a string, or migrate it from the number older noodles.toml files use
+func (v *NoodlesVersion) UnmarshalTOML(data interface{}) (decodeErr error) {
+	switch version := data.(type) {
+	case string:
+		v.SemanticVersion, decodeErr = ParseSemanticVersion(version)
+	case float64: // Such as 0.1, which is 0.1.0
+		parts := strings.SplitN(strconv.FormatFloat(version, 'f', -1, 64), ".", 2)
+
+		if len(parts) == 1 {
+			parts = append(parts, "0")
+		}
+
+		v.IsNumber = true
+		v.SemanticVersion, decodeErr = ParseSemanticVersion(parts[0] + "." + parts[1] + ".0")
+	case int64: // Such as 1, which is 1.0.0
+		v.IsNumber = true
+		v.SemanticVersion, decodeErr = ParseSemanticVersion(strconv.FormatInt(version, 10) + ".0.0")
+	default:
+		decodeErr = fmt.Errorf("Version must be a string, such as \"1.2.0\"")
+	}
+
+	return
+}
+
+// MarshalText will encode our Version as a string, migrating it from a number
+func (v NoodlesVersion) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
@@ -93,26 +93,37 @@ func HasDependencies(p NoodlesProject) (bool, []string) {
 	var depsMissing []string
 
 	if p.Plugin != "go" { // If the plugin isn't Go
-		pluginDepMap := DependenciesMap[p.Plugin] // Get the dependency map for this plugin
-		binaries := []string{pluginDepMap.Binary} // Set binaries to a slice of strings, where our initial string is our primary binary
+		depMapNames := []string{p.Plugin} // Set depMapNames to a slice of strings, where our initial string is our plugin
 
-		if p.Plugin == "typescript" && p.Compress { // If the project uses the Typescript plugin as well as compression (needs terser)
-			binaries = append(binaries, DependenciesMap["compress"].Binary) // Include our compression binary as well
+		if p.Plugin == "less" && p.Autoprefix { // If the project adds vendor prefixes to its CSS (needs postcss)
+			depMapNames = append(depMapNames, "autoprefix")
+		}
+
+		if p.Plugin == "less" && FindStylelintConfig(p.SourceDir) != "" { // If the project lints its LESS with stylelint
+			depMapNames = append(depMapNames, "stylelint")
+		}
+
+		if p.Plugin == "typescript" { // If the project uses the Typescript plugin
+			depMapNames = append(depMapNames, "eslint") // Include our linter as well
+
+			if p.Compress { // If the project uses compression as well (needs terser)
+				depMapNames = append(depMapNames, "compress") // Include our compression binary as well
+			}
 		}
 
 		if depsExist = coreutils.ExecutableExists("npm"); !depsExist { // If npm exists
 			depsMissing = []string{"nodejs"}
 		}
 
-		for _, binary := range binaries { // For each binary
-			if depsExist = coreutils.ExecutableExists(binary); !depsExist { // If the binary does not exist
-				if binary == "terser" { // If this is terser
-					pluginDepMap = DependenciesMap["compress"] // Change pluginDepMap to the one for compress
-				}
+		for _, depMapName := range depMapNames { // For each dependency map
+			pluginDepMap := DependenciesMap[depMapName]
 
+			if !coreutils.ExecutableExists(pluginDepMap.Binary) { // If the binary does not exist
 				depsMissing = append(depsMissing, pluginDepMap.Dependencies...) // Add the npm packages we may be missing
 			}
 		}
+
+		depsExist = len(depsMissing) == 0
 	} else { // If plugin is Go
 		if depsExist = coreutils.ExecutableExists("go"); !depsExist { // If go does not exist
 			depsMissing = []string{"go"}
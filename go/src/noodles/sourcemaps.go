@@ -0,0 +1,69 @@
+package main
+
+// This file contains functionality pertaining to keeping source maps intact as their files get renamed
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// sourceMappingURLRegex matches the sourceMappingURL comment of both JavaScript (//#) and CSS (/*# */)
+var sourceMappingURLRegex = regexp.MustCompile(`((?://|/\*)# sourceMappingURL=)[^\s*]+`)
+
+// GetSourceMapPath will return the path of the source map for the provided file
+func GetSourceMapPath(file string) string {
+	return file + ".map"
+}
+
+// RenameWithSourceMap will rename the provided file and, if it has one, its source map
+// The sourceMappingURL of the file and the file property of the source map will be updated to reflect the new name
+func RenameWithSourceMap(oldPath, newPath string) (renameErr error) {
+	if renameErr = os.Rename(oldPath, newPath); renameErr != nil { // Failed to rename the file itself
+		return
+	}
+
+	oldMapPath := GetSourceMapPath(oldPath)
+
+	if _, statErr := os.Stat(oldMapPath); statErr != nil { // No source map for this file
+		return
+	}
+
+	newMapPath := GetSourceMapPath(newPath)
+
+	if renameErr = os.Rename(oldMapPath, newMapPath); renameErr != nil { // Failed to rename the source map
+		return
+	}
+
+	var content []byte
+	if content, renameErr = ioutil.ReadFile(newPath); renameErr != nil { // Failed to read the renamed file
+		return
+	}
+
+	content = sourceMappingURLRegex.ReplaceAll(content, []byte("${1}"+filepath.Base(newMapPath))) // Point to our renamed source map
+
+	if renameErr = ioutil.WriteFile(newPath, content, 0644); renameErr != nil { // Failed to update the file
+		return
+	}
+
+	var mapContent []byte
+	if mapContent, renameErr = ioutil.ReadFile(newMapPath); renameErr != nil { // Failed to read the source map
+		return
+	}
+
+	sourceMap := make(map[string]interface{})
+
+	if renameErr = json.Unmarshal(mapContent, &sourceMap); renameErr != nil { // Failed to parse the source map
+		return
+	}
+
+	sourceMap["file"] = filepath.Base(newPath) // Update the file this source map belongs to
+
+	if mapContent, renameErr = json.Marshal(sourceMap); renameErr == nil {
+		renameErr = ioutil.WriteFile(newMapPath, mapContent, 0644)
+	}
+
+	return
+}
@@ -1,25 +1,33 @@
 package main
 
-// NoodlesCheckResult contains Deprecations, Errors, and Recommendations
+// NoodlesCheckResult contains Deprecations, Errors, Recommendations, and Warnings
 type NoodlesCheckResult map[string][]string
 
 // NoodlesProject is the configuration for Noodles Projects.
 type NoodlesProject struct {
-	AppendHash               bool `toml:"AppendHash,omitempty"`
-	Compress                 bool `toml:"Compress,omitempty"`
-	ConsolidateChildDirs     bool `toml:ConsolidateChildDirs,omitempty"`
+	AppendHash               bool     `toml:"AppendHash,omitempty"`
+	Autoprefix               bool     `toml:"Autoprefix,omitempty"`
+	Browsers                 []string `toml:"Browsers,omitempty"`
+	Compress                 bool     `toml:"Compress,omitempty"`
+	ConsolidateChildDirs     bool     `toml:"ConsolidateChildDirs,omitempty"`
 	Destination              string
 	DisableNestedEnvironment bool     `toml:"DisableNestedEnvironment,omitempty"`
 	EnableGoModules          bool     `toml:"EnableGoModules,omitempty"`
+	EntryPoints              []string `toml:"EntryPoints,omitempty"`
 	ExcludeItems             []string `toml:"ExcludeItems,omitempty"`
 	Flags                    []string
-	Mode                     string `toml:"Mode,omitempty"`
+	MinifierFlags            []string `toml:"MinifierFlags,omitempty"`
+	Mode                     string   `toml:"Mode,omitempty"`
+	ModuleDir                string   `toml:"ModuleDir,omitempty"`
+	Name                     string   `toml:"-"`
 	Plugin                   string
 	Private                  []string `toml:"Private,omitempty"`
 	Requires                 []string
 	SimpleName               string `toml:"SimpleName,omitempty"`
 	Source                   string
 	SourceDir                string `toml:"-"`
+	SourceMaps               bool   `toml:"SourceMaps,omitempty"`
+	TSConfig                 string `toml:"TSConfig,omitempty"`
 	TarballLocation          string `toml:"TarballLocation,omitempty"`
 	Target                   string `toml:"Target,omitempty"`
 	Type                     string `toml:"Type,omitempty"`
@@ -49,16 +57,25 @@ type NoodlesPlugin interface {
 	Run(n *NoodlesProject) error
 }
 
+// NoodlesIncrementalPlugin is an interface for plugins which know whether a project needs rebuilding
+type NoodlesIncrementalPlugin interface {
+	// IsUpToDate is a function that will return whether the project is unchanged since it was last built
+	IsUpToDate(n *NoodlesProject) bool
+}
+
 // NoodlesScript is the configuration for a Noodles Script
 type NoodlesScript struct {
-	Arguments   []string `toml:"Arguments,omitempty"`
-	Description string   `toml:"Description,omitempty"`
-	Directory   string   `toml:"Directory,omitempty"`
+	Arguments   []string          `toml:"Arguments,omitempty"`
+	Description string            `toml:"Description,omitempty"`
+	Directory   string            `toml:"Directory,omitempty"`
+	Env         map[string]string `toml:"Env,omitempty"` // Environment variables to set for the script, in addition to our own
 	Exec        string
-	File        string   `toml:"File,omitempty"`
-	Redirect    bool     `toml:"Redirect,omitempty"`
-	Requires    []string `toml:"Requires,omitempty"`
-	UseGoEnv    bool     `toml:"UseGoEnv,omitempty"`
+	File        string            `toml:"File,omitempty"`
+	Interactive bool              `toml:"Interactive,omitempty"` // Attach our stdin, for scripts which prompt or otherwise need input
+	Params      map[string]string `toml:"Params,omitempty"`      // Named parameters and their defaults, substituted into {{name}} in Arguments and set with --param name=value
+	Redirect    bool              `toml:"Redirect,omitempty"`
+	Requires    []string          `toml:"Requires,omitempty"`
+	UseGoEnv    bool              `toml:"UseGoEnv,omitempty"`
 }
 
 type validateFunc func(string) error
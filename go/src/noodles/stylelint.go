@@ -0,0 +1,137 @@
+package main
+
+// This file contains functionality pertaining to linting LESS projects with stylelint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// StylelintFileResult is the result of linting a single file, as output by stylelint --formatter json
+type StylelintFileResult struct {
+	Source   string
+	Warnings []StylelintWarning
+}
+
+// StylelintWarning is a single problem reported by stylelint, despite the name this includes errors
+type StylelintWarning struct {
+	Column   int
+	Line     int
+	Rule     string
+	Severity string
+	Text     string
+}
+
+// StylelintConfigFiles are the config file names stylelint supports, in the order stylelint itself prefers them
+var StylelintConfigFiles []string
+
+func init() {
+	StylelintConfigFiles = []string{".stylelintrc", ".stylelintrc.cjs", ".stylelintrc.js", ".stylelintrc.json", ".stylelintrc.mjs", ".stylelintrc.yaml", ".stylelintrc.yml", "stylelint.config.cjs", "stylelint.config.js", "stylelint.config.mjs"}
+}
+
+// FindStylelintConfig will look for a stylelint config from the provided directory up to our workdir, returning an empty string if there is none
+func FindStylelintConfig(dir string) string {
+	var configPath string
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workdir, dir)
+	}
+
+	for {
+		for _, configFile := range StylelintConfigFiles { // For each supported config file
+			if _, statErr := os.Stat(filepath.Join(dir, configFile)); statErr == nil { // Config exists
+				configPath = filepath.Join(dir, configFile)
+				break
+			}
+		}
+
+		if configPath == "" && PackageJSONHasKey(filepath.Join(dir, "package.json"), "stylelint") { // Config within package.json
+			configPath = filepath.Join(dir, "package.json")
+		}
+
+		if configPath != "" || dir == workdir || !strings.HasPrefix(dir, workdir) { // Found a config or reached the root of our workspace
+			break
+		}
+
+		dir = filepath.Dir(dir)
+	}
+
+	return configPath
+}
+
+// PackageJSONHasKey will return whether the provided package.json exists and has the top-level key
+func PackageJSONHasKey(packagePath, key string) bool {
+	var pkg map[string]json.RawMessage
+
+	if content, readErr := ioutil.ReadFile(packagePath); readErr == nil && json.Unmarshal(content, &pkg) == nil {
+		_, hasKey := pkg[key]
+		return hasKey
+	}
+
+	return false
+}
+
+// RunStylelint will run stylelint against the provided files with the provided config, parsing them as LESS
+func RunStylelint(configPath string, files []string, fix bool) (results []StylelintFileResult, lintErr error) {
+	args := []string{"--formatter", "json", "--custom-syntax", "postcss-less"}
+
+	if filepath.Base(configPath) != "package.json" { // stylelint only discovers package.json configs itself
+		args = append(args, "--config", configPath)
+	}
+
+	if fix { // Fix problems where possible
+		args = append(args, "--fix")
+	}
+
+	args = append(args, files...)
+
+	var stdout, stderr bytes.Buffer
+	linter := exec.Command(DependenciesMap["stylelint"].Binary, args...)
+	linter.Dir = filepath.Dir(configPath) // Ensure a package.json config is discovered
+	linter.Env = GetNpmGlobalEnv()        // So --custom-syntax finds our global postcss-less
+	linter.Stdout = &stdout
+	linter.Stderr = &stderr
+
+	runErr := linter.Run()
+	output := stdout.Bytes()
+
+	if len(bytes.TrimSpace(output)) == 0 { // Newer stylelint writes its results to stderr
+		output = stderr.Bytes()
+	}
+
+	if exitErr, isExitErr := runErr.(*exec.ExitError); runErr != nil && (!isExitErr || exitErr.ExitCode() != 2) { // Exit code 2 only means problems were found
+		lintErr = errors.New("stylelint failed: " + strings.TrimSpace(stderr.String()+"\n"+stdout.String()))
+		return
+	}
+
+	if parseErr := json.Unmarshal(output, &results); parseErr != nil { // Failed to parse the results
+		lintErr = fmt.Errorf("failed to parse stylelint output: %s\n%s", parseErr.Error(), stderr.String())
+	}
+
+	return
+}
+
+// StylelintDiagnostics will convert stylelint results into our diagnostics
+func StylelintDiagnostics(results []StylelintFileResult) (diagnostics []Diagnostic) {
+	for _, result := range results { // For each linted file
+		for _, warning := range result.Warnings { // For each problem
+			diagnostics = append(diagnostics, Diagnostic{
+				Code:     warning.Rule,
+				Column:   warning.Column,
+				File:     GetDiagnosticPath(result.Source, workdir),
+				Line:     warning.Line,
+				Message:  strings.TrimSuffix(warning.Text, " ("+warning.Rule+")"), // stylelint appends the rule to its text
+				Severity: warning.Severity,
+			})
+		}
+	}
+
+	return
+}
@@ -5,8 +5,10 @@ package main
 import (
 	"github.com/JoshStrobl/trunk"
 	"github.com/stroblindustries/coreutils"
+	"os"
 	"os/user"
 	"runtime"
+	"strings"
 )
 
 // CurrentUser is the current user running noodles
@@ -23,11 +25,21 @@ func init() {
 	CurrentUser = user.Username
 
 	DependenciesMap = map[string]DependencyMap{ // Map of all the deps you'll need based on project configuration options
+		"autoprefix": { // Vendor prefixing for LESS
+			Binary:       "postcss",
+			Dependencies: []string{"autoprefixer", "postcss", "postcss-cli"}, // PostCSS, its CLI and the autoprefixer plugin
+			Packager:     "npm",
+		},
 		"compress": { // Compression for TypeScript
 			Binary:       "terser",
 			Dependencies: []string{"terser"},
 			Packager:     "npm",
 		},
+		"eslint": { // Linting for TypeScript
+			Binary:       "eslint",
+			Dependencies: []string{"eslint", "@typescript-eslint/parser", "@typescript-eslint/eslint-plugin"}, // ESLint, TypeScript parser and rules
+			Packager:     "npm",
+		},
 		"go": { // Golang
 			Binary:       "go",
 			Dependencies: []string{"golang"},
@@ -48,6 +60,11 @@ func init() {
 			Dependencies: []string{"npm"},
 			Packager:     "system",
 		},
+		"stylelint": { // Linting for LESS, used when a project has a stylelint config
+			Binary:       "stylelint",
+			Dependencies: []string{"postcss-less", "stylelint"}, // stylelint and its LESS syntax
+			Packager:     "npm",
+		},
 		"typescript": {
 			Binary:       "tsc",
 			Dependencies: []string{"typescript"}, // closurecompiler and Typescript are needed
@@ -104,3 +121,29 @@ func PackageInstaller(packager string, dependencies []string) {
 	installOutput := coreutils.ExecCommand(packager, installFlags, true) // Call ExecCommand and output response to terminal
 	trunk.LogDebug(installOutput)
 }
+
+// GetNpmGlobalRoot will return the directory our globally installed npm packages live in, or an empty string if npm is not installed
+func GetNpmGlobalRoot() string {
+	if !coreutils.ExecutableExists("npm") { // No npm, so nothing was installed globally
+		return ""
+	}
+
+	return strings.TrimSpace(coreutils.ExecCommand("npm", []string{"root", "--global"}, true))
+}
+
+// GetNpmGlobalEnv will return our environment with our globally installed npm packages added to NODE_PATH, so tools can resolve the plugins setup installs
+func GetNpmGlobalEnv() []string {
+	env := os.Environ()
+
+	if npmRoot := GetNpmGlobalRoot(); npmRoot != "" {
+		nodePath := npmRoot
+
+		if existingPath := os.Getenv("NODE_PATH"); existingPath != "" { // Keep any paths already set
+			nodePath = existingPath + string(os.PathListSeparator) + npmRoot
+		}
+
+		env = append(env, "NODE_PATH="+nodePath)
+	}
+
+	return env
+}
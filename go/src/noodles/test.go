@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"github.com/JoshStrobl/trunk"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var testCmd = &cobra.Command{
+	Use:               "test",
+	Short:             "Runs available tests for projects",
+	Long:              "Runs available tests for projects",
+	Run:               test,
+	DisableAutoGenTag: true,
+}
+
+var testProject string
+
+func init() {
+	testCmd.Flags().StringVarP(&testProject, "project", "p", "", "Name of the project we're testing")
+}
+
+func test(cmd *cobra.Command, args []string) {
+	failed := false
+
+	if testProject == "" { // If no project is set
+		for name := range noodles.Projects { // For each project
+			if !TestProject(name) {
+				failed = true
+			}
+		}
+	} else { // If a specific project is set
+		failed = !TestProject(testProject)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// TestProject is responsible for running the respective tests for each project's type, returning whether they passed
+func TestProject(name string) (passed bool) {
+	passed = true
+
+	if project, exists := noodles.Projects[name]; exists { // If this project exists
+		if project.Plugin == "go" { // Go plugin
+			plugin := &goPlugin
+
+			trunk.LogInfo("Performing pre-run checks for " + name)
+			preRunErr := plugin.PreRun(&project)
+
+			if preRunErr != nil { // If there was an error during pre-run
+				trunk.LogErrRaw(fmt.Errorf("An error occurred during pre-run checks:\n%s\n", preRunErr.Error()))
+				passed = false
+				return
+			}
+
+			if testErr := plugin.Test(&project); testErr == nil { // Run the tests
+				trunk.LogSuccess(fmt.Sprintf("Tests passed for %s", name))
+			} else {
+				trunk.LogErrRaw(fmt.Errorf("An error occurred during testing:\n%s\n", testErr.Error()))
+				passed = false
+			}
+
+			trunk.LogInfo("Performing post-run for " + name)
+			postRunErr := plugin.PostRun(&project)
+
+			if postRunErr != nil { // If there was an error during post-run
+				trunk.LogErrRaw(fmt.Errorf("An error occurred during post-run:\n%s\n", postRunErr.Error()))
+			}
+		}
+	} else {
+		trunk.LogErr(name + " is not a valid project")
+		passed = false
+	}
+
+	return
+}
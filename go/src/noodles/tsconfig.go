@@ -0,0 +1,290 @@
+package main
+
+// This file contains functionality pertaining to reading and generating tsconfig.json files for TypeScript projects
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TSConfig is the subset of a tsconfig.json that we generate or inspect
+type TSConfig struct {
+	CompilerOptions map[string]interface{} `json:"compilerOptions"`
+	Files           []string               `json:"files,omitempty"`
+	Include         []string               `json:"include,omitempty"`
+	References      []TSConfigReference    `json:"references,omitempty"`
+}
+
+// TSConfigReference is a TypeScript project reference
+type TSConfigReference struct {
+	Path string `json:"path"`
+}
+
+// TypeScriptListOptions are compiler options which take a list rather than a single value
+var TypeScriptListOptions []string
+
+func init() {
+	TypeScriptListOptions = []string{"lib", "rootDirs", "typeRoots", "types"}
+}
+
+// GetGeneratedTSConfigPath will return the path to the tsconfig.json noodles generates for the provided project name
+func GetGeneratedTSConfigPath(name string) string {
+	return filepath.Join(workdir, ".noodles", "tsconfig."+name+".json")
+}
+
+// GetTSConfigPath will return the path to the tsconfig.json used by this project, either its own or our generated one
+func (n *NoodlesProject) GetTSConfigPath() string {
+	if n.TSConfig != "" { // Project provides its own tsconfig.json
+		return filepath.Join(workdir, n.TSConfig)
+	}
+
+	return GetGeneratedTSConfigPath(n.Name)
+}
+
+// GetTypeScriptRequires will return the names of TypeScript projects this project requires
+func (n *NoodlesProject) GetTypeScriptRequires() []string {
+	requires := []string{}
+
+	for _, required := range n.Requires { // For each required project or script
+		required = (strings.Split(required, ":"))[0]
+
+		if project, exists := noodles.Projects[required]; exists && project.Plugin == "typescript" { // TypeScript project
+			requires = append(requires, required)
+		}
+	}
+
+	return requires
+}
+
+// IsRequiredByTypeScript will return whether any other TypeScript project requires this project
+func (n *NoodlesProject) IsRequiredByTypeScript() bool {
+	var required bool
+
+	for _, project := range noodles.Projects { // For each project
+		if project.Plugin == "typescript" && ListContainsExact(project.GetTypeScriptRequires(), n.Name) {
+			required = true
+			break
+		}
+	}
+
+	return required
+}
+
+// ReadTSConfig will read a tsconfig.json, allowing for the comments and trailing commas tsc permits
+func ReadTSConfig(configPath string) (config TSConfig, readErr error) {
+	var content []byte
+
+	if content, readErr = ioutil.ReadFile(configPath); readErr != nil { // Failed to read the config
+		return
+	}
+
+	if readErr = json.Unmarshal(StripJSONComments(content), &config); readErr != nil { // Failed to parse the config
+		readErr = errors.New("failed to parse " + configPath + ": " + readErr.Error())
+	}
+
+	return
+}
+
+// StripJSONComments will remove comments and trailing commas from the provided JSON, leaving strings untouched
+func StripJSONComments(content []byte) []byte {
+	var stripped bytes.Buffer
+	inString := false
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+
+		if inString { // Inside of a string
+			stripped.WriteByte(c)
+
+			if c == '\\' && i+1 < len(content) { // Escaped character
+				i++
+				stripped.WriteByte(content[i])
+			} else if c == '"' { // End of string
+				inString = false
+			}
+
+			continue
+		}
+
+		if c == '"' { // Start of string
+			inString = true
+		} else if c == '/' && i+1 < len(content) && content[i+1] == '/' { // Line comment
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		} else if c == '/' && i+1 < len(content) && content[i+1] == '*' { // Block comment
+			i += 2
+
+			for i+1 < len(content) && !(content[i] == '*' && content[i+1] == '/') {
+				i++
+			}
+
+			i++ // Skip the trailing /
+			continue
+		} else if c == ',' { // Potential trailing comma
+			next := i + 1
+
+			for next < len(content) && strings.ContainsRune(" \t\r\n", rune(content[next])) {
+				next++
+			}
+
+			if next < len(content) && (content[next] == '}' || content[next] == ']') { // Trailing comma
+				continue
+			}
+		}
+
+		if i < len(content) {
+			stripped.WriteByte(content[i])
+		}
+	}
+
+	return stripped.Bytes()
+}
+
+// TypeScriptFlagsToOptions will convert tsc command line flags, such as --strict or --lib es2019,dom, into compilerOptions
+func TypeScriptFlagsToOptions(flags []string) map[string]interface{} {
+	options := make(map[string]interface{})
+
+	for i := 0; i < len(flags); i++ {
+		if !strings.HasPrefix(flags[i], "-") { // Not a flag
+			continue
+		}
+
+		name := strings.TrimLeft(flags[i], "-")
+		var value interface{} = true // Flags without a value are booleans
+
+		if strings.Contains(name, "=") { // --flag=value
+			parts := strings.SplitN(name, "=", 2)
+			name = parts[0]
+			value = parts[1]
+		} else if i+1 < len(flags) && !strings.HasPrefix(flags[i+1], "-") { // --flag value
+			i++
+			value = flags[i]
+		}
+
+		if stringValue, isString := value.(string); isString { // Convert the value to the type tsc expects
+			if ListContainsExact(TypeScriptListOptions, name) { // List of values
+				value = strings.Split(stringValue, ",")
+			} else if boolValue, boolErr := strconv.ParseBool(stringValue); boolErr == nil {
+				value = boolValue
+			} else if intValue, intErr := strconv.Atoi(stringValue); intErr == nil {
+				value = intValue
+			}
+		}
+
+		options[name] = value
+	}
+
+	return options
+}
+
+// GenerateTSConfig will generate the tsconfig.json for this project from its Mode, Target and Flags, and for any TypeScript projects it requires
+func (p *TypeScriptPlugin) GenerateTSConfig(n *NoodlesProject, generated map[string]bool) (generateErr error) {
+	if generated[n.Name] { // Already generated during this run
+		return
+	}
+
+	generated[n.Name] = true
+
+	for _, required := range n.GetTypeScriptRequires() { // Ensure our references exist before we reference them
+		requiredProject := noodles.Projects[required]
+		p.SetDefaults(&requiredProject)
+
+		if generateErr = p.GenerateTSConfig(&requiredProject, generated); generateErr != nil {
+			return
+		}
+	}
+
+	if n.TSConfig != "" { // Project provides its own tsconfig.json
+		return
+	}
+
+	if generateErr = p.Validate(n); generateErr != nil { // Mode or Target not supported by the installed tsc
+		generateErr = errors.New(n.Name + ": " + generateErr.Error())
+		return
+	}
+
+	configPath := GetGeneratedTSConfigPath(n.Name)
+	configDir := filepath.Dir(configPath)
+	relativeToConfig := func(path string) string { // Paths in tsconfig.json are relative to the config itself
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workdir, path)
+		}
+
+		relPath, _ := filepath.Rel(configDir, path)
+		return filepath.ToSlash(relPath)
+	}
+
+	options := TypeScriptFlagsToOptions(p.GetModeFlags(n.Mode))
+
+	if n.IsTypeScriptModule() { // Emit ES modules, unless our Flags say otherwise
+		options["module"] = "esnext"
+	}
+
+	for name, value := range TypeScriptFlagsToOptions(n.Flags) { // Project flags override those of our mode
+		options[name] = value
+	}
+
+	options["target"] = n.Target
+
+	if _, hasLib := options["lib"]; !hasLib { // No lib provided by our Flags
+		options["lib"] = p.GetLib(n.Target)
+	}
+	options["incremental"] = true
+
+	if n.IsTypeScriptModule() { // Each module is emitted to our output directory
+		options["outDir"] = relativeToConfig(n.Destination)
+	} else {
+		options["outFile"] = relativeToConfig(n.Destination)
+	}
+
+	if n.SourceMaps { // Emit source maps alongside our JavaScript
+		options["sourceMap"] = true
+	}
+
+	if n.IsRequiredByTypeScript() { // Referenced projects must be composite
+		options["composite"] = true
+	}
+
+	config := TSConfig{
+		CompilerOptions: options,
+	}
+
+	if n.IsTypeScriptModule() { // tsc will follow the imports of our entry points
+		for _, entryPoint := range n.EntryPoints {
+			config.Files = append(config.Files, relativeToConfig(entryPoint))
+		}
+	} else if strings.Contains(filepath.Base(n.Source), "*") { // Globbed source
+		config.Include = []string{relativeToConfig(n.Source)}
+	} else {
+		config.Files = []string{relativeToConfig(n.Source)}
+	}
+
+	for _, required := range n.GetTypeScriptRequires() { // For each TypeScript project we require
+		requiredProject := noodles.Projects[required]
+		config.References = append(config.References, TSConfigReference{Path: relativeToConfig(requiredProject.GetTSConfigPath())})
+	}
+
+	var content []byte
+	if content, generateErr = json.MarshalIndent(config, "", "\t"); generateErr != nil { // Failed to encode our config
+		return
+	}
+
+	content = append(content, '\n')
+
+	if existingContent, readErr := ioutil.ReadFile(configPath); readErr == nil && bytes.Equal(existingContent, content) { // Unchanged, don't touch it so tsc --build stays incremental
+		return
+	}
+
+	if generateErr = os.MkdirAll(configDir, 0755); generateErr == nil { // Created our config dir
+		generateErr = ioutil.WriteFile(configPath, content, 0644)
+	}
+
+	return
+}
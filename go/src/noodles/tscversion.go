@@ -0,0 +1,182 @@
+package main
+
+// This file contains functionality pertaining to detecting the installed tsc and what it supports
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TypeScriptVersion is a version of the TypeScript compiler
+type TypeScriptVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// TypeScriptFlagVersions is the tsc version which introduced each of the flags used by our Modes
+var TypeScriptFlagVersions map[string]TypeScriptVersion
+
+// TypeScriptTargetVersions is the tsc version which introduced each of the targets in ValidTypeScriptTargets
+var TypeScriptTargetVersions map[string]TypeScriptVersion
+
+var detectedTypeScriptVersion *TypeScriptVersion // Cached result of tsc --version
+var tscVersionRegex = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+func init() {
+	TypeScriptFlagVersions = map[string]TypeScriptVersion{
+		"--declaration":                      {1, 0, 0},
+		"--forceConsistentCasingInFileNames": {1, 8, 0},
+		"--noFallthroughCasesInSwitch":       {1, 8, 0},
+		"--noImplicitOverride":               {4, 3, 0},
+		"--noImplicitReturns":                {1, 8, 0},
+		"--noUnusedLocals":                   {2, 0, 0},
+		"--noUnusedParameters":               {2, 0, 0},
+		"--removeComments":                   {1, 0, 0},
+		"--strict":                           {2, 3, 0},
+	}
+
+	TypeScriptTargetVersions = map[string]TypeScriptVersion{
+		"ES5":    {1, 0, 0},
+		"ES2015": {1, 8, 0},
+		"ES2016": {2, 1, 0},
+		"ES2017": {2, 1, 0},
+		"ES2018": {2, 7, 0},
+		"ES2019": {3, 6, 0},
+		"ES2020": {3, 8, 0},
+		"ES2021": {4, 2, 0},
+		"ES2022": {4, 6, 0},
+		"ES2023": {5, 0, 0},
+		"ES2024": {5, 7, 0},
+		"ESNext": {2, 1, 0},
+	}
+}
+
+// AtLeast will return whether this version is the same as or newer than the provided version
+func (v TypeScriptVersion) AtLeast(min TypeScriptVersion) bool {
+	if v.Major != min.Major {
+		return v.Major > min.Major
+	}
+
+	if v.Minor != min.Minor {
+		return v.Minor > min.Minor
+	}
+
+	return v.Patch >= min.Patch
+}
+
+// String will return the version as major.minor.patch
+func (v TypeScriptVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// ParseTypeScriptVersion will parse the output of tsc --version, such as "Version 5.4.5"
+func ParseTypeScriptVersion(output string) (version TypeScriptVersion, parseErr error) {
+	parts := tscVersionRegex.FindStringSubmatch(output)
+
+	if parts == nil { // No version in the output
+		parseErr = errors.New("unable to determine the tsc version from: " + strings.TrimSpace(output))
+		return
+	}
+
+	version.Major, _ = strconv.Atoi(parts[1])
+	version.Minor, _ = strconv.Atoi(parts[2])
+
+	if parts[3] != "" { // Has a patch version
+		version.Patch, _ = strconv.Atoi(parts[3])
+	}
+
+	return
+}
+
+// GetVersion will return the version of the installed tsc, querying it only once
+func (p *TypeScriptPlugin) GetVersion() (version TypeScriptVersion, versionErr error) {
+	if detectedTypeScriptVersion != nil { // Already detected
+		return *detectedTypeScriptVersion, nil
+	}
+
+	output, execErr := exec.Command(DependenciesMap["typescript"].Binary, "--version").CombinedOutput()
+
+	if execErr != nil { // Failed to run tsc
+		versionErr = errors.New("failed to get the tsc version: " + execErr.Error())
+		return
+	}
+
+	if version, versionErr = ParseTypeScriptVersion(string(output)); versionErr == nil {
+		detectedTypeScriptVersion = &version
+	}
+
+	return
+}
+
+// GetValidTargets will return the targets supported by the installed tsc, or all known targets if tsc can't be queried
+func (p *TypeScriptPlugin) GetValidTargets() []string {
+	version, versionErr := p.GetVersion()
+	targets := []string{}
+
+	for _, target := range ValidTypeScriptTargets { // For each known target
+		if versionErr != nil || version.AtLeast(TypeScriptTargetVersions[target]) { // Supported
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
+
+// GetDefaultTarget will return the latest formal specification supported by the installed tsc
+func (p *TypeScriptPlugin) GetDefaultTarget() string {
+	var defaultTarget string
+
+	for _, target := range p.GetValidTargets() { // Targets are ordered oldest to newest
+		if target != "ESNext" { // Not a formal specification
+			defaultTarget = target
+		}
+	}
+
+	return defaultTarget
+}
+
+// GetLib will return the lib setting for the provided target, being its own library alongside the DOM
+func (p *TypeScriptPlugin) GetLib(target string) []string {
+	return []string{strings.ToLower(target), "dom", "dom.iterable"}
+}
+
+// NormalizeTarget will return the target in the casing we use, or an error if the installed tsc does not support it
+func (p *TypeScriptPlugin) NormalizeTarget(target string) (normalized string, targetErr error) {
+	validTargets := p.GetValidTargets()
+
+	for _, validTarget := range validTargets { // For each valid target
+		if strings.EqualFold(validTarget, target) { // tsc itself is case insensitive
+			normalized = validTarget
+			return
+		}
+	}
+
+	if version, versionErr := p.GetVersion(); versionErr == nil { // Know which tsc we validated against
+		targetErr = fmt.Errorf("Target %s is not supported by tsc %s. Must be one of: %s", target, version.String(), strings.Join(validTargets, ", "))
+	} else {
+		targetErr = fmt.Errorf("Target %s is not valid. Must be one of: %s", target, strings.Join(validTargets, ", "))
+	}
+
+	return
+}
+
+// Validate will ensure the Mode and Target of the project are valid for the installed tsc
+func (p *TypeScriptPlugin) Validate(n *NoodlesProject) (validateErr error) {
+	if !ListContainsExact(ValidTypeScriptModes, strings.ToLower(n.Mode)) { // Not a valid mode
+		validateErr = fmt.Errorf("Mode %s is not valid. Must be one of: %s", n.Mode, strings.Join(ValidTypeScriptModes, ", "))
+		return
+	}
+
+	var target string
+
+	if target, validateErr = p.NormalizeTarget(n.Target); validateErr == nil { // Valid target
+		n.Target = target
+	}
+
+	return
+}
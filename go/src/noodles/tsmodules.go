@@ -0,0 +1,235 @@
+package main
+
+// This file contains functionality pertaining to TypeScript projects emitting ES modules from multiple entry points
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// importSpecifierRegex matches relative import specifiers in static imports, exports and dynamic imports, minified or not
+var importSpecifierRegex = regexp.MustCompile(`((?:\bfrom|\bimport)\s*\(?\s*["'])(\.\.?/[^"'\n]+)(["'])`)
+
+// hashedJSFileRegex matches JavaScript files we've already appended a hash to
+var hashedJSFileRegex = regexp.MustCompile(`-[0-9a-f]{40}(\.min)?\.js$`)
+
+// IsTypeScriptModule will return whether this TypeScript project compiles entry points to ES modules in an output directory
+func (n *NoodlesProject) IsTypeScriptModule() bool {
+	return len(n.EntryPoints) != 0
+}
+
+// GetTypeScriptSourceDirs will return the sorted, unique directories of the project's EntryPoints, or the directory of its Source
+func (n *NoodlesProject) GetTypeScriptSourceDirs() []string {
+	if !n.IsTypeScriptModule() { // Single Source
+		return []string{filepath.Dir(n.Source)}
+	}
+
+	dirs := []string{}
+
+	for _, entryPoint := range n.EntryPoints {
+		if dir := filepath.Dir(entryPoint); !ListContainsExact(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	sort.Strings(dirs)
+	return dirs
+}
+
+// GetHashedJSFileName will return the provided .js or .min.js file path with the hash appended to its base name
+func GetHashedJSFileName(file, hash string) string {
+	extension := ".js"
+
+	if strings.HasSuffix(file, ".min.js") { // Minified
+		extension = ".min.js"
+	}
+
+	return strings.TrimSuffix(file, extension) + "-" + hash + extension
+}
+
+// GetEmittedJSFiles will return all JavaScript files emitted by tsc in the provided directory, excluding any we've minified or hashed
+func GetEmittedJSFiles(dir string) (files []string, getErr error) {
+	getErr = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && strings.HasSuffix(path, ".js") && !strings.HasSuffix(path, ".min.js") && !hashedJSFileRegex.MatchString(path) { // Emitted by tsc
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// ResolveImportSpecifier will resolve a relative import specifier from the importer to one of the files in the mapping
+// It returns the file the specifier resolved to, the new specifier pointing to its mapped file, and whether it resolved at all
+func ResolveImportSpecifier(importer, specifier string, mapping map[string]string) (resolvedSource string, newSpecifier string, resolved bool) {
+	importerDir := filepath.Dir(importer)
+	target := filepath.Join(importerDir, specifier)
+
+	for _, candidate := range []string{target, target + ".js", filepath.Join(target, "index.js")} { // Specifiers may omit the extension or point to a directory
+		if mapped, exists := mapping[candidate]; exists {
+			relPath, _ := filepath.Rel(importerDir, mapped)
+			newSpecifier = filepath.ToSlash(relPath)
+
+			if !strings.HasPrefix(newSpecifier, ".") { // Relative specifiers must start with ./ or ../
+				newSpecifier = "./" + newSpecifier
+			}
+
+			resolvedSource = candidate
+			resolved = true
+			break
+		}
+	}
+
+	return
+}
+
+// RewriteImportSpecifiers will rewrite the relative import specifiers in content, which was emitted as importer, to point to the mapped files
+func RewriteImportSpecifiers(content []byte, importer string, mapping map[string]string) []byte {
+	return importSpecifierRegex.ReplaceAllFunc(content, func(match []byte) []byte {
+		parts := importSpecifierRegex.FindSubmatch(match)
+
+		if _, newSpecifier, resolved := ResolveImportSpecifier(importer, string(parts[2]), mapping); resolved {
+			return []byte(string(parts[1]) + newSpecifier + string(parts[3]))
+		}
+
+		return match
+	})
+}
+
+// GetImportedSources will return the emitted files, from those provided, that the content emitted as importer imports
+func GetImportedSources(content []byte, importer string, sources []string) []string {
+	identity := make(map[string]string)
+
+	for _, source := range sources {
+		identity[source] = source
+	}
+
+	imported := []string{}
+
+	for _, parts := range importSpecifierRegex.FindAllSubmatch(content, -1) { // For each relative import
+		if resolvedSource, _, resolved := ResolveImportSpecifier(importer, string(parts[2]), identity); resolved && resolvedSource != importer {
+			imported = append(imported, resolvedSource)
+		}
+	}
+
+	return imported
+}
+
+// PostRunModules will minify and hash each emitted ES module, rewriting the import specifiers between them
+func (p *TypeScriptPlugin) PostRunModules(n *NoodlesProject) (postRunErr error) {
+	var sources []string
+	if sources, postRunErr = GetEmittedJSFiles(n.Destination); postRunErr != nil { // Failed to get our emitted files
+		return
+	}
+
+	sort.Strings(sources)
+	finals := make(map[string]string) // Emitted file to the file we'll ship, such as its .min.js
+
+	for _, source := range sources { // For each emitted file
+		finals[source] = source
+
+		if n.AppendHash { // Remove hashes from previous builds
+			RemoveHashedFiles(filepath.Dir(source), ".js", strings.TrimSuffix(filepath.Base(source), ".js"))
+		}
+
+		if n.Compress { // Minify each emitted file
+			if finals[source], postRunErr = p.Minify(n, source); postRunErr != nil {
+				return
+			}
+		}
+	}
+
+	names := make(map[string]string) // Emitted file to the final name of the file we'll ship
+
+	for source, final := range finals {
+		names[source] = final
+	}
+
+	if n.AppendHash { // Determine our hashed names, hashing imported files first so an importer's hash reflects those of its imports
+		hashed := make(map[string]bool)
+		pending := sources
+
+		for len(pending) != 0 {
+			var next []string
+
+			for _, source := range pending {
+				if p.ImportsHashed(source, finals[source], sources, hashed) { // All imports are hashed
+					if postRunErr = p.HashModule(source, finals[source], names, hashed); postRunErr != nil {
+						return
+					}
+				} else {
+					next = append(next, source)
+				}
+			}
+
+			if len(next) == len(pending) { // Circular imports, so hash one of them with what we know so far
+				if postRunErr = p.HashModule(next[0], finals[next[0]], names, hashed); postRunErr != nil {
+					return
+				}
+
+				next = next[1:]
+			}
+
+			pending = next
+		}
+	}
+
+	for _, source := range sources { // For each emitted file, point its imports to the final names and rename it
+		final := finals[source]
+
+		var content []byte
+		if content, postRunErr = ioutil.ReadFile(final); postRunErr != nil {
+			return
+		}
+
+		if postRunErr = ioutil.WriteFile(final, RewriteImportSpecifiers(content, source, names), 0644); postRunErr != nil {
+			return
+		}
+
+		if names[source] != final { // Hashed
+			if postRunErr = RenameWithSourceMap(final, names[source]); postRunErr != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// ImportsHashed will return whether every emitted file imported by the provided module has been hashed
+func (p *TypeScriptPlugin) ImportsHashed(source, final string, sources []string, hashed map[string]bool) bool {
+	content, readErr := ioutil.ReadFile(final)
+
+	if readErr != nil { // Let HashModule report the read failure
+		return true
+	}
+
+	for _, imported := range GetImportedSources(content, source, sources) {
+		if !hashed[imported] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HashModule will determine the hashed name of the module, after pointing its imports to the names known so far
+func (p *TypeScriptPlugin) HashModule(source, final string, names map[string]string, hashed map[string]bool) error {
+	content, readErr := ioutil.ReadFile(final)
+
+	if readErr == nil {
+		names[source] = GetHashedJSFileName(final, CreateHash(RewriteImportSpecifiers(content, source, names)))
+		hashed[source] = true
+	}
+
+	return readErr
+}
@@ -11,7 +11,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 )
 
 // CreateHash will create a sha1sum of the provided bytes
@@ -33,14 +35,26 @@ func CopyFile(source, destination string) error {
 	}
 
 	if copyFileErr == nil {
-		if destinationFile, createErr := os.OpenFile(destination, os.O_RDWR|os.O_CREATE, 0755); createErr == nil { // Create a file to copy the contents into
+		mode := os.FileMode(0644)
+
+		if info, statErr := os.Stat(source); statErr == nil { // Keep the permissions of the source, such as whether it is executable
+			mode = info.Mode().Perm()
+		}
+
+		if destinationFile, createErr := os.OpenFile(destination, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode); createErr == nil { // Create a file to copy the contents into
 			if sourceFile, openErr := os.OpenFile(source, os.O_RDONLY, 0755); openErr == nil {
-				io.Copy(destinationFile, sourceFile) // Copy the contents
-				sourceFile.Close()                   // Close project file
-				destinationFile.Close()              // Close the temporary destination file
+				if _, ioErr := io.Copy(destinationFile, sourceFile); ioErr != nil { // Failed to copy the contents
+					copyFileErr = errors.New("failed to copy " + source + ": " + ioErr.Error())
+				}
+
+				sourceFile.Close() // Close project file
 			} else {
 				copyFileErr = errors.New("failed to open " + source + ": " + openErr.Error())
 			}
+
+			if closeErr := destinationFile.Close(); copyFileErr == nil && closeErr != nil { // Close the temporary destination file
+				copyFileErr = errors.New("failed to write " + destination + ": " + closeErr.Error())
+			}
 		} else {
 			copyFileErr = errors.New("failed to create " + destination + ": " + createErr.Error())
 		}
@@ -74,6 +88,20 @@ func ListContains(list []string, substring string) bool {
 	return contains
 }
 
+// ListContainsExact will check if a string array contains the exact string
+func ListContainsExact(list []string, str string) bool {
+	var contains bool
+
+	for _, s := range list {
+		if s == str {
+			contains = true
+			break
+		}
+	}
+
+	return contains
+}
+
 // PromptErrorCheck will check if we have a valid error from a prompt and if so, display and exit.
 func PromptErrorCheck(promptErr error) {
 	if promptErr != nil { // If we failed to get the prompt result
@@ -99,17 +127,43 @@ func PromptExtensionValidate(expectedType, input string) error {
 
 // RemoveHashedFiles will remove any existing hashed files of a given type and file base name from the destination
 func RemoveHashedFiles(destination, t, fileBaseName string) {
+	hashedFileRegex := regexp.MustCompile("^" + regexp.QuoteMeta(fileBaseName) + "-[0-9a-f]{40}[.]") // Base name followed by our sha1 hash, so we don't remove files that merely share a prefix
+
 	if files, getErr := coreutils.GetFilesContains(destination, t); getErr == nil { // Got respective files from the destination directory
 		for _, file := range files { // For each file
 			fileName := filepath.Base(file)
 
-			if strings.HasPrefix(fileName, fileBaseName+"-") { // Existing file with hash
+			if hashedFileRegex.MatchString(fileName) { // Existing file with hash
 				os.Remove(file) // Remove file
 			}
 		}
 	}
 }
 
+// GetHashedFileName will return the name of the file in the directory which AppendHash renamed the provided file to, or an empty string if there is none
+func GetHashedFileName(dir, file string) (hashed string) {
+	extension := filepath.Ext(file)
+
+	if strings.HasSuffix(file, ".min.js") { // Hash is appended before .min.js
+		extension = ".min.js"
+	}
+
+	baseName := strings.TrimSuffix(file, extension)
+	hashedFileRegex := regexp.MustCompile("^" + regexp.QuoteMeta(baseName) + "-[0-9a-f]{40}" + regexp.QuoteMeta(extension) + "$")
+	matches, _ := filepath.Glob(filepath.Join(dir, baseName+"-*"+extension))
+
+	var newest time.Time
+
+	for _, match := range matches { // For each possibly hashed file
+		if info, statErr := os.Stat(match); statErr == nil && hashedFileRegex.MatchString(filepath.Base(match)) && info.ModTime().After(newest) { // Most recently built
+			hashed = filepath.Base(match)
+			newest = info.ModTime()
+		}
+	}
+
+	return
+}
+
 // TextPromptValidate will get the requested input based on the message and validate it against our validate func
 func TextPromptValidate(message string, validate validateFunc) string {
 	var response string
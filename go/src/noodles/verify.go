@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"github.com/JoshStrobl/trunk"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:               "verify [archive...]",
+	Short:             "Verify packed archives against their checksums and signatures",
+	Long:              "Verify the provided archives, or every archive, package and image tarball pack creates, against the SHA256SUMS alongside them and, when signed, their minisign signatures",
+	Run:               verify,
+	DisableAutoGenTag: true,
+}
+
+var verifyPublicKey string
+
+func init() {
+	verifyCmd.Flags().StringVarP(&verifyPublicKey, "public-key", "k", "", "Path to the minisign public key, regardless of NOODLES_PUBLIC_KEY or Distribution PublicKey")
+}
+
+func verify(cmd *cobra.Command, args []string) {
+	archives := args
+
+	if len(archives) == 0 { // No archives provided
+		var artifactsErr error
+		if archives, artifactsErr = GetDistributionArtifacts(noodles.Projects); artifactsErr != nil {
+			trunk.LogFatal(artifactsErr.Error())
+		}
+	}
+
+	keyPath := verifyPublicKey
+
+	if keyPath == "" {
+		keyPath = GetPublicKeyPath()
+	}
+
+	var key *MinisignPublicKey
+
+	if keyPath != "" { // Have a public key, so every archive must be signed
+		if publicKey, readErr := ReadMinisignPublicKey(keyPath); readErr == nil {
+			key = &publicKey
+		} else {
+			trunk.LogFatal("Failed to read the public key: " + readErr.Error())
+		}
+	}
+
+	failed := false
+
+	for _, archive := range archives {
+		if verifyErr := VerifyArchive(archive, key); verifyErr == nil {
+			trunk.LogSuccess("Verified " + archive)
+		} else {
+			trunk.LogErr(verifyErr.Error())
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// VerifyArchive will verify the archive against the SHA256SUMS alongside it and, if we have a public key, its signature
+func VerifyArchive(archive string, key *MinisignPublicKey) (verifyErr error) {
+	var checksums map[string]string
+	if checksums, verifyErr = ReadChecksums(filepath.Join(filepath.Dir(archive), ChecksumsFile)); verifyErr != nil { // Failed to read our checksums
+		return
+	}
+
+	expected, exists := checksums[filepath.Base(archive)]
+
+	if !exists { // Not in our checksums
+		verifyErr = fmt.Errorf("%s is not listed in %s", archive, ChecksumsFile)
+		return
+	}
+
+	var digest string
+	if digest, verifyErr = GetFileSHA256(archive); verifyErr != nil { // Failed to hash the archive
+		return
+	}
+
+	if digest != expected { // Changed since it was packed
+		verifyErr = fmt.Errorf("%s does not match %s: sha256 %s, expected %s", archive, ChecksumsFile, digest, expected)
+		return
+	}
+
+	_, statErr := os.Stat(archive + MinisignSignatureExtension)
+
+	if key == nil { // No public key to check a signature with
+		if statErr == nil {
+			verifyErr = fmt.Errorf("%s is signed, but no public key was provided with --public-key, NOODLES_PUBLIC_KEY or Distribution PublicKey", archive)
+		}
+
+		return
+	}
+
+	if statErr != nil { // Not signed
+		verifyErr = fmt.Errorf("%s has no %s signature", archive, MinisignSignatureExtension)
+		return
+	}
+
+	verifyErr = VerifyMinisign(archive, *key)
+	return
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/JoshStrobl/trunk"
+	"github.com/spf13/cobra"
+	"github.com/stroblindustries/coreutils"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var versionCmd = &cobra.Command{
+	Use:               "version",
+	Short:             "Show the version of the workspace",
+	Long:              "Show the semantic version of the workspace, as set by Version in noodles.toml",
+	Run:               version,
+	DisableAutoGenTag: true,
+}
+
+var versionBumpCmd = &cobra.Command{
+	Use:               "bump major|minor|patch|pre",
+	Short:             "Bump the version of the workspace",
+	Long:              "Bump the major, minor, patch or pre-release version of the workspace in noodles.toml, optionally committing it and creating a git tag",
+	Args:              cobra.ExactArgs(1),
+	ValidArgs:         []string{"major", "minor", "patch", "pre"},
+	Run:               versionBump,
+	DisableAutoGenTag: true,
+}
+
+var versionPreID string
+var versionTag bool
+
+// configVersionRegex matches the top-level Version of noodles.toml, whether it is a string or a number
+var configVersionRegex = regexp.MustCompile(`(?m)^Version\s*=\s*(?:"[^"\n]*"|'[^'\n]*'|[0-9.+\-eE_]+)`)
+
+// configTableRegex matches the header of a table, or array of tables, in noodles.toml
+var configTableRegex = regexp.MustCompile(`(?m)^[ \t]*\[`)
+
+func init() {
+	versionBumpCmd.Flags().StringVarP(&versionPreID, "preid", "", "rc", "Identifier of pre-releases, such as rc in 2.0.0-rc.1")
+	versionBumpCmd.Flags().BoolVarP(&versionTag, "tag", "t", false, "Commit noodles.toml and create a v-prefixed git tag of the new version")
+	versionCmd.AddCommand(versionBumpCmd)
+}
+
+func version(cmd *cobra.Command, args []string) {
+	fmt.Println(noodles.Version.String())
+
+	if noodles.Version.IsNumber { // Using the deprecated form
+		trunk.LogWarn("Version is written as a number in noodles.toml. Use noodles version bump, or write it as \"" + noodles.Version.String() + "\".")
+	}
+}
+
+func versionBump(cmd *cobra.Command, args []string) {
+	bumped, bumpErr := noodles.Version.Bump(args[0], versionPreID)
+
+	if bumpErr != nil {
+		trunk.LogFatal(bumpErr.Error())
+	}
+
+	if versionTag && !coreutils.ExecutableExists("git") { // Can't tag without git
+		trunk.LogFatal("git does not exist on the system.")
+	}
+
+	if updateErr := UpdateConfigVersion(filepath.Join(workdir, "noodles.toml"), bumped); updateErr != nil {
+		trunk.LogFatal("Failed to update noodles.toml: " + updateErr.Error())
+	}
+
+	trunk.LogSuccess("Bumped version from " + noodles.Version.String() + " to " + bumped.String())
+	noodles.Version = NoodlesVersion{SemanticVersion: bumped}
+
+	if versionTag { // Commit our new version and tag it
+		tag := "v" + bumped.String()
+
+		if gitErr := RunGit("commit", "-m", "Bump version to "+bumped.String(), "--", "noodles.toml"); gitErr != nil {
+			trunk.LogFatal("Failed to commit noodles.toml: " + gitErr.Error())
+		}
+
+		if gitErr := RunGit("tag", "-a", tag, "-m", bumped.String()); gitErr != nil {
+			trunk.LogFatal("Failed to create the tag " + tag + ": " + gitErr.Error())
+		}
+
+		trunk.LogSuccess("Created tag " + tag)
+	}
+}
+
+// UpdateConfigVersion will set the Version of the noodles.toml at the provided path, leaving the rest of it as is
+func UpdateConfigVersion(configPath string, version SemanticVersion) (updateErr error) {
+	var content []byte
+	if content, updateErr = ioutil.ReadFile(configPath); updateErr != nil {
+		return
+	}
+
+	config := string(content)
+	line := "Version = \"" + version.String() + "\""
+	firstTable := len(config)
+
+	if tableIndex := configTableRegex.FindStringIndex(config); tableIndex != nil { // Top-level keys must precede our first table
+		firstTable = tableIndex[0]
+	}
+
+	if location := configVersionRegex.FindStringIndex(config[:firstTable]); location != nil { // Replace our existing Version
+		config = config[:location[0]] + line + config[location[1]:]
+	} else if firstTable == len(config) && config != "" && !strings.HasSuffix(config, "\n") { // No tables, and no trailing newline
+		config += "\n" + line + "\n"
+	} else { // Add it before our first table
+		config = config[:firstTable] + line + "\n" + config[firstTable:]
+	}
+
+	var conf NoodlesConfig
+
+	if _, decodeErr := toml.Decode(config, &conf); decodeErr != nil { // Ensure we still have a valid config
+		updateErr = errors.New("failed to set Version: " + decodeErr.Error())
+		return
+	}
+
+	updateErr = coreutils.WriteOrUpdateFile(configPath, []byte(config), coreutils.NonGlobalFileMode)
+	return
+}
+
+// RunGit will run git in our workdir with the provided args, showing its output
+func RunGit(args ...string) error {
+	gitCmd := exec.Command("git", args...)
+	gitCmd.Dir = workdir
+	gitCmd.Stdout = os.Stdout
+	gitCmd.Stderr = os.Stderr
+
+	return gitCmd.Run()
+}